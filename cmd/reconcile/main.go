@@ -0,0 +1,84 @@
+// Command reconcile finds and repairs drift between goals and their goal
+// nodes: missing or mislabelled nodes, node balances and targets that don't
+// match the goal, goal transactions that don't sum to the goal's current
+// amount, and leftovers of deleted goals. It uses the same configuration as
+// the server. Take a backup first; with -dry-run nothing is changed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/thejoshbq/vault-x/internal/config"
+	"github.com/thejoshbq/vault-x/internal/database"
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report drift without repairing it")
+	deleteOrphans := flag.Bool("delete-orphans", false, "delete goal nodes no goal links to, with their flows")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	// A staged restore replaces the database when the server next starts, so
+	// anything repaired now would be thrown away
+	if _, err := os.Stat(database.PendingRestorePath(cfg.DatabasePath)); err == nil {
+		log.Fatalf("A restore is staged for %s; restart the server to apply it before reconciling", cfg.DatabasePath)
+	}
+
+	db, err := database.Open(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	kek, err := fieldcrypt.LoadKEK(cfg.FieldEncryptionKey, cfg.FieldEncryptionKeyFile)
+	if err != nil {
+		log.Fatalf("Invalid field encryption key: %v", err)
+	}
+	retired, err := fieldcrypt.ParseKEKs(cfg.FieldEncryptionPreviousKeys)
+	if err != nil {
+		log.Fatalf("Invalid previous field encryption key: %v", err)
+	}
+	keyring, err := fieldcrypt.Open(db, kek, retired)
+	if err != nil {
+		log.Fatalf("Failed to open encryption keyring: %v", err)
+	}
+
+	store := services.NewStore(db, keyring)
+	drift, err := store.ReconcileGoals(services.ReconcileOptions{
+		Repair:        !*dryRun,
+		DeleteOrphans: *deleteOrphans,
+	})
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	for _, d := range drift {
+		fmt.Printf("goal=%d node=%d %s: %s\n", d.GoalID, d.NodeID, d.Kind, d.Detail)
+	}
+	switch {
+	case len(drift) == 0:
+		fmt.Println("No drift found")
+	case *dryRun:
+		fmt.Printf("%d problems found; run without -dry-run to repair\n", len(drift))
+	default:
+		fmt.Printf("%d problems repaired\n", len(drift))
+		if !*deleteOrphans {
+			for _, d := range drift {
+				if d.Kind == services.DriftOrphanNode {
+					fmt.Println("Orphan goal nodes were kept; use -delete-orphans to remove them")
+					break
+				}
+			}
+		}
+	}
+}
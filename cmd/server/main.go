@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"github.com/thejoshbq/vault-x/internal/backup"
 	"github.com/thejoshbq/vault-x/internal/config"
 	"github.com/thejoshbq/vault-x/internal/database"
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
 	"github.com/thejoshbq/vault-x/internal/handlers"
+	"github.com/thejoshbq/vault-x/internal/jobs"
 	"github.com/thejoshbq/vault-x/internal/middleware"
+	"github.com/thejoshbq/vault-x/internal/services"
 )
 
 func main() {
@@ -31,6 +37,106 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Field-level encryption: load the keyring, then seal any plaintext rows
+	kek, err := fieldcrypt.LoadKEK(cfg.FieldEncryptionKey, cfg.FieldEncryptionKeyFile)
+	if err != nil {
+		log.Fatalf("Invalid field encryption key: %v", err)
+	}
+	retired, err := fieldcrypt.ParseKEKs(cfg.FieldEncryptionPreviousKeys)
+	if err != nil {
+		log.Fatalf("Invalid previous field encryption key: %v", err)
+	}
+	keyring, err := fieldcrypt.Open(db, kek, retired)
+	if err != nil {
+		log.Fatalf("Failed to open encryption keyring: %v", err)
+	}
+	if n, err := keyring.EncryptExisting(db); err != nil {
+		log.Fatalf("Failed to encrypt existing data: %v", err)
+	} else if n > 0 {
+		log.Printf("Encrypted %d existing values", n)
+	}
+	// Drop data keys a rotation had to keep because a value was still using them
+	if _, err := keyring.PruneKeys(db); err != nil {
+		log.Fatalf("Failed to prune encryption keys: %v", err)
+	}
+
+	// Background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var remote *backup.Remote
+	if cfg.S3Endpoint != "" {
+		client, err := backup.NewS3Client(backup.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+		if err != nil {
+			log.Fatalf("Invalid S3 backup config: %v", err)
+		}
+		var key []byte
+		if cfg.BackupEncryptionKey != "" {
+			if key, err = backup.ParseKey(cfg.BackupEncryptionKey); err != nil {
+				log.Fatalf("Invalid backup encryption key: %v", err)
+			}
+		}
+		remote = backup.NewRemote(client, cfg.S3Prefix, key)
+	}
+
+	backups := backup.NewManager(db, backup.Options{
+		Dir:          cfg.BackupDir,
+		DatabasePath: cfg.DatabasePath,
+		Retention: backup.Retention{
+			Hourly: cfg.BackupKeepHourly,
+			Daily:  cfg.BackupKeepDaily,
+			Weekly: cfg.BackupKeepWeekly,
+		},
+		Remote: remote,
+	})
+	jobs.Every(ctx, "backup", cfg.BackupInterval, backups.Run)
+
+	compounding := services.Compounding(cfg.InterestCompounding)
+	if !compounding.Valid() {
+		log.Fatalf("Invalid INTEREST_COMPOUNDING %q (want daily or monthly)", cfg.InterestCompounding)
+	}
+	store := services.NewStore(db, keyring)
+	// Goal transactions from before kinds were recorded are classified by note
+	if n, err := store.ClassifyGoalTransactions(); err != nil {
+		log.Fatalf("Failed to classify goal transactions: %v", err)
+	} else if n > 0 {
+		log.Printf("Classified %d goal transactions", n)
+	}
+	jobs.Every(ctx, "interest", cfg.InterestInterval, func(ctx context.Context) error {
+		n, err := store.AccrueInterest(time.Now(), compounding)
+		if n > 0 {
+			log.Printf("Accrued interest: %d entries", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "balance-snapshot", cfg.BalanceSnapshotInterval, func(ctx context.Context) error {
+		n, err := store.SnapshotBalances(time.Now())
+		if n > 0 {
+			log.Printf("Recorded balance snapshots: %d nodes", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "goal-contributions", cfg.GoalContributionInterval, func(ctx context.Context) error {
+		n, err := store.PostGoalContributions(time.Now())
+		if n > 0 {
+			log.Printf("Posted goal contributions: %d", n)
+		}
+		return err
+	})
+	jobs.Every(ctx, "sinking-funds", cfg.SinkingFundInterval, func(ctx context.Context) error {
+		n, err := store.PaySinkingFunds(time.Now())
+		if n > 0 {
+			log.Printf("Paid bills from sinking funds: %d", n)
+		}
+		return err
+	})
+
 	// Create Fiber app with minimal memory config
 	app := fiber.New(fiber.Config{
 		AppName:       "Budget System v2.0.26",
@@ -54,7 +160,7 @@ func main() {
 	}))
 
 	// Initialize handlers
-	h := handlers.New(db, cfg)
+	h := handlers.New(db, cfg, backups, keyring)
 
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -76,6 +182,10 @@ func main() {
 	// Protected routes
 	protected := api.Group("/", middleware.JWTAuth(cfg.JWTSecret))
 
+	// Account backup (all profiles)
+	protected.Get("/export", h.ExportUser)
+	protected.Post("/import", h.ImportUser)
+
 	// Profile routes
 	profiles := protected.Group("/profiles")
 	profiles.Get("/", h.ListProfiles)
@@ -90,8 +200,32 @@ func main() {
 	profiles.Put("/:profileId/nodes/:nodeId", h.UpdateNode)
 	profiles.Delete("/:profileId/nodes/:nodeId", h.DeleteNode)
 
+	// Balance routes
+	profiles.Get("/:profileId/nodes/:nodeId/accruals", h.ListInterestAccruals)
+	profiles.Get("/:profileId/nodes/:nodeId/history", h.GetNodeHistory)
+	profiles.Get("/:profileId/net-worth/history", h.GetNetWorthHistory)
+
+	// Investment routes
+	profiles.Get("/:profileId/nodes/:nodeId/holdings", h.ListHoldings)
+	profiles.Post("/:profileId/nodes/:nodeId/holdings", h.CreateHolding)
+	profiles.Delete("/:profileId/holdings/:holdingId", h.DeleteHolding)
+	profiles.Get("/:profileId/holdings/:holdingId/transactions", h.ListHoldingTransactions)
+	profiles.Post("/:profileId/holdings/:holdingId/transactions", h.CreateHoldingTransaction)
+	profiles.Delete("/:profileId/holdings/:holdingId/transactions/:txId", h.DeleteHoldingTransaction)
+	profiles.Post("/:profileId/prices", h.SetPrice)
+	profiles.Post("/:profileId/prices/import", h.ImportPrices)
+	profiles.Get("/:profileId/prices/:symbol", h.ListPrices)
+	profiles.Get("/:profileId/investments", h.GetInvestmentSummary)
+
+	// Debt routes
+	profiles.Get("/:profileId/nodes/:nodeId/amortization", h.GetAmortization)
+	profiles.Get("/:profileId/debt-plan", h.GetDebtPlan)
+
 	// Flow routes (Sankey diagram)
 	profiles.Get("/:profileId/flows", h.ListFlows)
+	profiles.Get("/:profileId/flows/validate", h.ValidateFlows)
+	profiles.Get("/:profileId/sankey", h.GetSankey)
+	profiles.Get("/:profileId/sankey/render", h.RenderSankey)
 	profiles.Post("/:profileId/flows", h.CreateFlow)
 	profiles.Put("/:profileId/flows/:flowId", h.UpdateFlow)
 	profiles.Delete("/:profileId/flows/:flowId", h.DeleteFlow)
@@ -118,9 +252,55 @@ func main() {
 	profiles.Post("/:profileId/goals/:goalId/transactions", h.CreateGoalTransaction)
 	profiles.Delete("/:profileId/goals/:goalId/transactions/:txId", h.DeleteGoalTransaction)
 
+	// Goal milestone and lifecycle routes
+	profiles.Get("/:profileId/goals/:goalId/milestones", h.ListGoalMilestones)
+	profiles.Post("/:profileId/goals/:goalId/milestones", h.CreateGoalMilestone)
+	profiles.Delete("/:profileId/goals/:goalId/milestones/:milestoneId", h.DeleteGoalMilestone)
+	profiles.Get("/:profileId/goals/:goalId/history", h.GetGoalHistory)
+	profiles.Post("/:profileId/goals/:goalId/archive", h.ArchiveGoal)
+	profiles.Post("/:profileId/goals/:goalId/unarchive", h.UnarchiveGoal)
+
+	// Sinking fund routes
+	profiles.Get("/:profileId/sinking-funds", h.ListSinkingFunds)
+	profiles.Post("/:profileId/sinking-funds", h.CreateSinkingFund)
+	profiles.Delete("/:profileId/sinking-funds/:fundId", h.DeleteSinkingFund)
+
+	// Anomaly routes (unusual transactions flagged when they're added)
+	profiles.Get("/:profileId/anomalies", h.ListAnomalies)
+	profiles.Post("/:profileId/anomalies/:anomalyId/dismiss", h.DismissAnomaly)
+	profiles.Post("/:profileId/anomalies/:anomalyId/confirm", h.ConfirmAnomaly)
+
 	// Dashboard aggregation
 	profiles.Get("/:profileId/dashboard", h.GetDashboard)
 	profiles.Get("/:profileId/forecast", h.GetForecast)
+	profiles.Get("/:profileId/cashflow", h.GetCashFlow)
+	profiles.Get("/:profileId/simulation", h.GetSimulation)
+
+	// Reports
+	profiles.Get("/:profileId/reports/spending", h.GetSpendingReport)
+
+	// Scenario routes (what-if overlays on nodes and flows)
+	profiles.Get("/:profileId/scenarios", h.ListScenarios)
+	profiles.Post("/:profileId/scenarios", h.CreateScenario)
+	profiles.Get("/:profileId/scenarios/:scenarioId", h.GetScenario)
+	profiles.Put("/:profileId/scenarios/:scenarioId", h.UpdateScenario)
+	profiles.Delete("/:profileId/scenarios/:scenarioId", h.DeleteScenario)
+	profiles.Get("/:profileId/scenarios/:scenarioId/compare", h.CompareScenario)
+	profiles.Post("/:profileId/scenarios/:scenarioId/promote", h.PromoteScenario)
+	profiles.Post("/:profileId/scenarios/:scenarioId/changes", h.CreateScenarioChange)
+	profiles.Delete("/:profileId/scenarios/:scenarioId/changes/:changeId", h.DeleteScenarioChange)
+
+	// Export
+	profiles.Get("/:profileId/export/ledger", h.ExportLedger)
+
+	// Admin routes
+	admin := protected.Group("/admin", middleware.RequireAdmin(cfg.AdminEmails))
+	admin.Get("/backups", h.ListBackups)
+	admin.Post("/backups", h.CreateBackup)
+	admin.Get("/backups/:name/verify", h.VerifyBackup)
+	admin.Post("/backups/restore", h.RestoreBackup)
+	admin.Get("/encryption", h.EncryptionStatus)
+	admin.Post("/encryption/rotate", h.RotateEncryptionKey)
 
 	// Serve static files (React build)
 	app.Static("/", "./web/dist")
@@ -0,0 +1,268 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	filePrefix = "budget-"
+	fileSuffix = ".db"
+	timeLayout = "20060102-150405"
+)
+
+// Retention is a grandfather-father-son policy: keep the newest snapshot in
+// each of the last Hourly hours, Daily days and Weekly ISO weeks.
+type Retention struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+}
+
+type Options struct {
+	Dir          string
+	DatabasePath string // Restores are staged next to the live database
+	Retention    Retention
+	Remote       *Remote // Optional off-box copy of every snapshot
+}
+
+// Manager takes consistent snapshots of the live database with VACUUM INTO,
+// which is safe under WAL unlike copying the database file.
+type Manager struct {
+	db        *sql.DB
+	dir       string
+	dbPath    string
+	retention Retention
+	remote    *Remote
+	mu        sync.Mutex
+}
+
+func NewManager(db *sql.DB, opts Options) *Manager {
+	return &Manager{
+		db:        db,
+		dir:       opts.Dir,
+		dbPath:    opts.DatabasePath,
+		retention: opts.Retention,
+		remote:    opts.Remote,
+	}
+}
+
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Create writes a new snapshot, verifies it and applies retention
+func (m *Manager) Create(ctx context.Context) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now().UTC()
+	name := filePrefix + now.Format(timeLayout) + fileSuffix
+	path := filepath.Join(m.dir, name)
+	tmp := path + ".tmp"
+
+	// VACUUM INTO refuses to overwrite, so clear any leftover from a crash
+	os.Remove(tmp)
+
+	if _, err := m.db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("snapshot failed: %w", err)
+	}
+
+	if err := Verify(tmp); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.prune(); err != nil {
+		return nil, fmt.Errorf("snapshot written but pruning failed: %w", err)
+	}
+
+	if m.remote != nil {
+		if err := m.remote.Upload(ctx, name, path); err != nil {
+			return nil, fmt.Errorf("snapshot %s saved locally but upload failed: %w", name, err)
+		}
+		if err := m.remote.prune(ctx, m.retention); err != nil {
+			return nil, fmt.Errorf("snapshot %s uploaded but remote pruning failed: %w", name, err)
+		}
+	}
+
+	return &Snapshot{Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// Run is the scheduled job entry point
+func (m *Manager) Run(ctx context.Context) error {
+	_, err := m.Create(ctx)
+	return err
+}
+
+// List returns snapshots newest first
+func (m *Manager) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		createdAt, ok := parseName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// Path resolves a snapshot name to its file, rejecting anything that isn't a
+// snapshot in the backup directory
+func (m *Manager) Path(name string) (string, error) {
+	if _, ok := parseName(name); !ok || filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return filepath.Join(m.dir, name), nil
+}
+
+func (m *Manager) prune() ([]string, error) {
+	snapshots, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := retained(snapshots, m.retention)
+
+	var removed []string
+	for _, s := range snapshots {
+		if keep[s.Name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.dir, s.Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, s.Name)
+	}
+
+	return removed, nil
+}
+
+// retained picks which snapshots (sorted newest first) survive the policy.
+// The newest snapshot is always kept.
+func retained(snapshots []Snapshot, r Retention) map[string]bool {
+	keep := map[string]bool{}
+	if len(snapshots) == 0 {
+		return keep
+	}
+	keep[snapshots[0].Name] = true
+
+	bucket := func(limit int, key func(time.Time) string) {
+		seen := map[string]bool{}
+		for _, s := range snapshots {
+			if len(seen) >= limit {
+				return
+			}
+			k := key(s.CreatedAt)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			keep[s.Name] = true
+		}
+	}
+
+	bucket(r.Hourly, func(t time.Time) string { return t.Format("2006010215") })
+	bucket(r.Daily, func(t time.Time) string { return t.Format("20060102") })
+	bucket(r.Weekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+
+	return keep
+}
+
+// Verify opens a snapshot read-only and runs SQLite's integrity check
+func Verify(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("integrity check failed: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("snapshot failed integrity check: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+	t, err := time.Parse(timeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted snapshots start with a magic string so restores can tell sealed
+// and plain objects apart. v1 sealed the whole file as one AES-256-GCM
+// message and is still read; v2 seals it in chunks so neither side holds
+// the snapshot in memory.
+var (
+	encryptedMagicV1 = []byte("VXBAK1")
+	encryptedMagic   = []byte("VXBAK2")
+)
+
+const (
+	// chunkSize is the plaintext sealed per chunk
+	chunkSize = 64 << 10
+
+	// noncePrefixSize is the random part of each chunk's nonce; the rest is
+	// a 4-byte chunk counter and a 1-byte last-chunk flag
+	noncePrefixSize = 7
+)
+
+// ParseKey decodes a base64 AES-256 key (e.g. from `openssl rand -base64 32`)
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// sealWriter encrypts a stream as magic | nonce prefix | chunks. Each chunk
+// is up to chunkSize bytes of plaintext sealed with AES-256-GCM under a
+// nonce of the prefix, its index and whether it is the last chunk, so
+// chunks can't be reordered, dropped or cut off at the end unnoticed.
+type sealWriter struct {
+	w      io.Writer
+	gcm    cipher.AEAD
+	prefix []byte
+	index  uint32
+	buf    []byte
+	out    []byte
+}
+
+// newSealWriter writes the header to w and returns a writer that seals
+// everything written to it. Close seals the last chunk; it doesn't close w.
+func newSealWriter(key []byte, w io.Writer) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(append([]byte{}, encryptedMagic...), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &sealWriter{
+		w:      w,
+		gcm:    gcm,
+		prefix: prefix,
+		buf:    make([]byte, 0, chunkSize),
+		out:    make([]byte, 0, chunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data shows it isn't the last
+		if len(s.buf) == chunkSize {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):chunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (s *sealWriter) Close() error {
+	return s.flush(true)
+}
+
+func (s *sealWriter) flush(last bool) error {
+	s.out = s.gcm.Seal(s.out[:0], chunkNonce(s.prefix, s.index, last), s.buf, encryptedMagic)
+	if _, err := s.w.Write(s.out); err != nil {
+		return err
+	}
+	s.index++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// openReader decrypts what sealWriter wrote
+type openReader struct {
+	r      *bufio.Reader
+	gcm    cipher.AEAD
+	prefix []byte
+	index  uint32
+	sealed []byte
+	plain  []byte
+	done   bool
+}
+
+// newOpenReader reads the header from r and returns a reader of the
+// decrypted snapshot. A read fails if any chunk doesn't authenticate,
+// including when the stream was cut short.
+func newOpenReader(key []byte, r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !(bytes.Equal(magic, encryptedMagic) || bytes.Equal(magic, encryptedMagicV1)) {
+		return nil, errors.New("snapshot is not encrypted")
+	}
+	if key == nil {
+		return nil, errors.New("snapshot is encrypted but no BACKUP_ENCRYPTION_KEY is configured")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(magic, encryptedMagicV1) {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := openV1(gcm, data)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(plain), nil
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, errors.New("encrypted snapshot is truncated")
+	}
+	return &openReader{
+		r:      br,
+		gcm:    gcm,
+		prefix: prefix,
+		sealed: make([]byte, chunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.plain) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.plain)
+	o.plain = o.plain[n:]
+	return n, nil
+}
+
+// next decrypts the following chunk. A chunk is the last one when the
+// stream ends with it.
+func (o *openReader) next() error {
+	n, err := io.ReadFull(o.r, o.sealed)
+	switch err {
+	case nil:
+		if _, err := o.r.Peek(1); err == io.EOF {
+			o.done = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF, io.EOF:
+		o.done = true
+	default:
+		return err
+	}
+
+	plain, err := o.gcm.Open(o.sealed[:0], chunkNonce(o.prefix, o.index, o.done), o.sealed[:n], encryptedMagic)
+	if err != nil {
+		return errors.New("failed to decrypt snapshot (wrong key, or corrupted or truncated object)")
+	}
+	o.index++
+	o.plain = plain
+	return nil
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// openV1 decrypts a snapshot sealed whole: nonce | ciphertext
+func openV1(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted snapshot is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, ciphertext, encryptedMagicV1)
+	if err != nil {
+		return nil, errors.New("failed to decrypt snapshot (wrong key or corrupted object)")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,125 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sealBytes(t *testing.T, key, plain []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := newSealWriter(key, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Odd-sized writes cross chunk boundaries
+	for len(plain) > 0 {
+		n := min(len(plain), 10007)
+		if _, err := w.Write(plain[:n]); err != nil {
+			t.Fatal(err)
+		}
+		plain = plain[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func openBytes(key, sealed []byte) ([]byte, error) {
+	r, err := newOpenReader(key, bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 12345} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		sealed := sealBytes(t, key, plain)
+		if !bytes.HasPrefix(sealed, encryptedMagic) {
+			t.Fatalf("size %d: sealed snapshot doesn't start with %q", size, encryptedMagic)
+		}
+		got, err := openBytes(key, sealed)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: round trip returned %d different bytes", size, len(got))
+		}
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	key := testKey(t)
+	plain := make([]byte, 2*chunkSize+100)
+	rand.Read(plain)
+	sealed := sealBytes(t, key, plain)
+	header := len(encryptedMagic) + noncePrefixSize
+	chunk := chunkSize + 16
+
+	flipped := append([]byte{}, sealed...)
+	flipped[header+chunk+5] ^= 1
+
+	swapped := append([]byte{}, sealed[:header]...)
+	swapped = append(swapped, sealed[header+chunk:header+2*chunk]...)
+	swapped = append(swapped, sealed[header:header+chunk]...)
+	swapped = append(swapped, sealed[header+2*chunk:]...)
+
+	tests := []struct {
+		name   string
+		key    []byte
+		sealed []byte
+	}{
+		{name: "wrong key", key: testKey(t), sealed: sealed},
+		{name: "flipped bit", key: key, sealed: flipped},
+		{name: "chunks reordered", key: key, sealed: swapped},
+		{name: "last chunk dropped", key: key, sealed: sealed[:header+2*chunk]},
+		{name: "cut mid-chunk", key: key, sealed: sealed[:header+chunk+100]},
+		{name: "header only", key: key, sealed: sealed[:header]},
+		{name: "no key", sealed: sealed},
+		{name: "not encrypted", key: key, sealed: plain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := openBytes(tt.key, tt.sealed); err == nil {
+				t.Error("opened a tampered snapshot")
+			}
+		})
+	}
+}
+
+func TestOpenV1(t *testing.T) {
+	key := testKey(t)
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	sealed := append(append([]byte{}, encryptedMagicV1...), nonce...)
+	sealed = gcm.Seal(sealed, nonce, []byte("SQLite format 3"), encryptedMagicV1)
+
+	got, err := openBytes(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "SQLite format 3" {
+		t.Errorf("got %q", got)
+	}
+}
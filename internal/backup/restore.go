@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/database"
+)
+
+// RestoreRequest picks a snapshot by exact name, or the newest snapshot taken
+// at or before At (point-in-time recovery). Remote pulls from the bucket.
+type RestoreRequest struct {
+	Name   string
+	At     time.Time
+	Remote bool
+}
+
+// ErrNoSnapshot is returned when no snapshot matches a restore request
+var ErrNoSnapshot = errors.New("no snapshot matches the request")
+
+func (m *Manager) HasRemote() bool {
+	return m.remote != nil
+}
+
+func (m *Manager) ListRemote(ctx context.Context) ([]Snapshot, error) {
+	if m.remote == nil {
+		return nil, errors.New("remote backups are not configured")
+	}
+	return m.remote.List(ctx)
+}
+
+// StageRestore fetches and verifies a snapshot, then stages it next to the
+// live database. It is swapped in on the next start, because replacing the
+// file under open connections would corrupt it.
+func (m *Manager) StageRestore(ctx context.Context, req RestoreRequest) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var snapshots []Snapshot
+	var err error
+	if req.Remote {
+		if m.remote == nil {
+			return nil, errors.New("remote backups are not configured")
+		}
+		snapshots, err = m.remote.List(ctx)
+	} else {
+		snapshots, err = m.List()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, ok := choose(snapshots, req)
+	if !ok {
+		return nil, ErrNoSnapshot
+	}
+
+	staged := database.PendingRestorePath(m.dbPath)
+	tmp := staged + ".tmp"
+	if err := m.fetch(ctx, snapshot, req.Remote, tmp); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to fetch %s: %w", snapshot.Name, err)
+	}
+	if err := Verify(tmp); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, staged); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// fetch streams a local or remote snapshot to path
+func (m *Manager) fetch(ctx context.Context, snapshot Snapshot, remote bool, path string) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if remote {
+		err = m.remote.Download(ctx, snapshot, out)
+	} else {
+		var in *os.File
+		if in, err = os.Open(filepath.Join(m.dir, snapshot.Name)); err == nil {
+			_, err = io.Copy(out, in)
+			in.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// choose finds the requested snapshot in a newest-first list
+func choose(snapshots []Snapshot, req RestoreRequest) (Snapshot, bool) {
+	for _, s := range snapshots {
+		if req.Name != "" {
+			if s.Name == req.Name {
+				return s, true
+			}
+			continue
+		}
+		if req.At.IsZero() || !s.CreatedAt.After(req.At) {
+			return s, true
+		}
+	}
+	return Snapshot{}, false
+}
@@ -0,0 +1,204 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+const (
+	// Canvases up to this many pixels are drawn at twice the size and
+	// scaled down, which smooths the edges of the curves
+	supersampleLimit = 4_000_000
+
+	fontScale     = 2 // Font pixels per canvas pixel
+	titleScale    = 3
+	curveSegments = 32
+)
+
+// SankeyPNG renders a Sankey graph as a PNG image, laid out exactly as
+// SankeySVG lays it out
+func SankeyPNG(graph models.SankeyResponse, opts Options) ([]byte, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	l := layoutSankey(graph, opts)
+
+	ss := 1.0
+	if opts.Width*opts.Height <= supersampleLimit {
+		ss = 2
+	}
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0, int(float64(opts.Width)*ss), int(float64(opts.Height)*ss))),
+		ss:  ss,
+	}
+
+	bg, _ := ParseColor(opts.Background)
+	c.fillRect(0, 0, float64(opts.Width), float64(opts.Height), bg, 1)
+	ink, _ := ParseColor(l.ink)
+
+	y := float64(marginY)
+	if opts.Title != "" {
+		c.text(marginX, y, opts.Title, ink, 1, titleScale)
+		y += titleSpace
+	}
+	for i, col := range l.columns {
+		x := col.x
+		if i == len(l.columns)-1 {
+			x = col.x + nodeWidth - float64(textWidth(col.name)*fontScale)
+		}
+		c.text(x, y+2, col.name, ink, 0.7, fontScale)
+	}
+
+	for _, d := range l.bands {
+		cx := (d.x0 + d.x1) / 2
+		var pts []point
+		for i := 0; i <= curveSegments; i++ {
+			pts = append(pts, bezier(d.x0, d.y0, cx, d.y0, cx, d.y1, d.x1, d.y1, float64(i)/curveSegments))
+		}
+		for i := curveSegments; i >= 0; i-- {
+			pts = append(pts, bezier(d.x0, d.y0+d.thickness, cx, d.y0+d.thickness, cx, d.y1+d.thickness, d.x1, d.y1+d.thickness, float64(i)/curveSegments))
+		}
+		c.fillPolygon(pts, parseOr(d.color), linkOpacity)
+	}
+
+	for _, n := range l.boxes {
+		c.fillRect(n.x, n.y, n.x+nodeWidth, n.y+n.h, parseOr(n.node.Color), 1)
+
+		label := n.label()
+		x := n.x + nodeWidth + labelPad
+		if !n.labelRight {
+			x = n.x - labelPad - float64(textWidth(label)*fontScale)
+		}
+		c.text(x, n.y+n.h/2-glyphHeight*fontScale/2, label, ink, 1, fontScale)
+	}
+
+	var out image.Image = c.img
+	if ss > 1 {
+		out = downsample(c.img, int(ss))
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, out); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+type point struct{ x, y float64 }
+
+func bezier(x0, y0, x1, y1, x2, y2, x3, y3, t float64) point {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return point{a*x0 + b*x1 + c*x2 + d*x3, a*y0 + b*y1 + c*y2 + d*y3}
+}
+
+func parseOr(s string) color.RGBA {
+	c, err := ParseColor(s)
+	if err != nil {
+		c, _ = ParseColor(fallbackColor)
+	}
+	return c
+}
+
+// canvas draws in chart coordinates onto an image ss times larger
+type canvas struct {
+	img *image.RGBA
+	ss  float64
+}
+
+// blend paints one pixel with c at opacity alpha over what is there
+func (c *canvas) blend(x, y int, col color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(c.img.Rect)) {
+		return
+	}
+	i := c.img.PixOffset(x, y)
+	p := c.img.Pix[i : i+4 : i+4]
+	p[0] = uint8(float64(p[0])*(1-alpha) + float64(col.R)*alpha + 0.5)
+	p[1] = uint8(float64(p[1])*(1-alpha) + float64(col.G)*alpha + 0.5)
+	p[2] = uint8(float64(p[2])*(1-alpha) + float64(col.B)*alpha + 0.5)
+	p[3] = 255
+}
+
+func (c *canvas) fillRect(x0, y0, x1, y1 float64, col color.RGBA, alpha float64) {
+	for y := int(math.Round(y0 * c.ss)); y < int(math.Round(y1*c.ss)); y++ {
+		for x := int(math.Round(x0 * c.ss)); x < int(math.Round(x1*c.ss)); x++ {
+			c.blend(x, y, col, alpha)
+		}
+	}
+}
+
+// fillPolygon fills a simple polygon by scanline, sampling each row at the
+// pixel centers
+func (c *canvas) fillPolygon(pts []point, col color.RGBA, alpha float64) {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i := range pts {
+		pts[i].x *= c.ss
+		pts[i].y *= c.ss
+		minY, maxY = math.Min(minY, pts[i].y), math.Max(maxY, pts[i].y)
+	}
+
+	var xs []float64
+	for y := int(math.Floor(minY)); y <= int(math.Ceil(maxY)); y++ {
+		sy := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			if (a.y <= sy) != (b.y <= sy) {
+				xs = append(xs, a.x+(sy-a.y)*(b.x-a.x)/(b.y-a.y))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := int(math.Round(xs[i])); x < int(math.Round(xs[i+1])); x++ {
+				c.blend(x, y, col, alpha)
+			}
+		}
+	}
+}
+
+// text draws s with the bitmap font, its top-left corner at (x, y)
+func (c *canvas) text(x, y float64, s string, col color.RGBA, alpha float64, scale int) {
+	px := float64(scale)
+	for _, r := range s {
+		g := glyph(r)
+		for row, bits := range g {
+			for i, bit := range bits {
+				if bit == '#' {
+					c.fillRect(x+float64(i)*px, y+float64(row)*px, x+float64(i+1)*px, y+float64(row+1)*px, col, alpha)
+				}
+			}
+		}
+		x += float64(glyphWidth+glyphSpacing) * px
+	}
+}
+
+// downsample averages factor×factor blocks of pixels
+func downsample(src *image.RGBA, factor int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/factor, b.Dy()/factor))
+	n := uint32(factor * factor)
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			var sum [4]uint32
+			for dy := 0; dy < factor; dy++ {
+				i := src.PixOffset(x*factor, y*factor+dy)
+				for dx := 0; dx < factor; dx++ {
+					for k := 0; k < 4; k++ {
+						sum[k] += uint32(src.Pix[i+dx*4+k])
+					}
+				}
+			}
+			j := dst.PixOffset(x, y)
+			for k := 0; k < 4; k++ {
+				dst.Pix[j+k] = uint8(sum[k] / n)
+			}
+		}
+	}
+	return dst
+}
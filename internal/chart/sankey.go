@@ -0,0 +1,235 @@
+// Package chart renders charts to SVG and PNG without a browser, for emails
+// and reports. Only the standard library is used: PNGs are rasterized here
+// with a built-in bitmap font.
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Size limits for a rendered chart
+const (
+	MinSize = 200
+	MaxSize = 4000
+)
+
+// Options control the canvas. Zero values take the defaults.
+type Options struct {
+	Width      int    // Default 900
+	Height     int    // Default 520
+	Background string // #rrggbb, default white
+	Title      string // Drawn above the columns when set
+}
+
+func (o *Options) normalize() error {
+	if o.Width == 0 {
+		o.Width = 900
+	}
+	if o.Height == 0 {
+		o.Height = 520
+	}
+	if o.Width < MinSize || o.Width > MaxSize || o.Height < MinSize || o.Height > MaxSize {
+		return fmt.Errorf("width and height must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Background == "" {
+		o.Background = "#ffffff"
+	}
+	if _, err := ParseColor(o.Background); err != nil {
+		return errors.New("background must be a #rrggbb color")
+	}
+	return nil
+}
+
+// Geometry shared by the SVG and PNG renderers, in canvas pixels
+const (
+	nodeWidth   = 14
+	nodeGap     = 10
+	marginX     = 12
+	headerSpace = 28
+	titleSpace  = 28
+	marginY     = 12
+	labelPad    = 6
+	linkOpacity = 0.45
+)
+
+type box struct {
+	node       models.SankeyNode
+	x, y, h    float64
+	outY, inY  float64 // Next free offset for outgoing and incoming links
+	labelRight bool
+}
+
+type band struct {
+	color     string
+	x0, x1    float64
+	y0, y1    float64 // Top edge at source and target
+	thickness float64
+}
+
+type layout struct {
+	opts    Options
+	columns []columnHeader
+	boxes   []*box
+	bands   []band
+	ink     string // Text color, chosen for contrast with the background
+}
+
+type columnHeader struct {
+	name string
+	x    float64
+}
+
+// layoutSankey places nodes in their columns with heights proportional to
+// value, and stacks links on each node in the order of the node at their
+// other end so bands don't cross needlessly
+func layoutSankey(graph models.SankeyResponse, opts Options) *layout {
+	l := &layout{opts: opts, ink: "#18181b"}
+	if bg, _ := ParseColor(opts.Background); luminance(bg) < 0.5 {
+		l.ink = "#f4f4f5"
+	}
+
+	top := float64(marginY + headerSpace)
+	if opts.Title != "" {
+		top += titleSpace
+	}
+	avail := float64(opts.Height) - top - marginY
+
+	ncols := len(graph.Columns)
+	if ncols < 2 {
+		ncols = 2
+	}
+	colX := func(c int) float64 {
+		return marginX + float64(c)*(float64(opts.Width)-2*marginX-nodeWidth)/float64(ncols-1)
+	}
+	for _, c := range graph.Columns {
+		l.columns = append(l.columns, columnHeader{name: c.Name, x: colX(c.Index)})
+	}
+
+	byColumn := map[int][]models.SankeyNode{}
+	for _, n := range graph.Nodes {
+		byColumn[n.Column] = append(byColumn[n.Column], n)
+	}
+
+	// One scale for every column so equal money is equal height
+	scale := math.Inf(1)
+	for _, nodes := range byColumn {
+		total := 0.0
+		for _, n := range nodes {
+			total += n.Value
+		}
+		if total > 0 {
+			scale = math.Min(scale, (avail-float64(len(nodes)-1)*nodeGap)/total)
+		}
+	}
+	if math.IsInf(scale, 1) || scale < 0 {
+		scale = 0
+	}
+
+	boxes := map[int64]*box{}
+	for c, nodes := range byColumn {
+		used := float64(len(nodes)-1) * nodeGap
+		for _, n := range nodes {
+			used += math.Max(n.Value*scale, 2)
+		}
+		y := top + math.Max(0, (avail-used)/2)
+		for _, n := range nodes {
+			b := &box{node: n, x: colX(c), y: y, h: math.Max(n.Value*scale, 2), labelRight: c < ncols-1}
+			boxes[n.ID] = b
+			l.boxes = append(l.boxes, b)
+			y += b.h + nodeGap
+		}
+	}
+	sort.SliceStable(l.boxes, func(i, j int) bool { return l.boxes[i].x < l.boxes[j].x })
+
+	links := append([]models.SankeyLink{}, graph.Links...)
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := boxes[links[i].Target], boxes[links[j].Target]
+		if a == nil || b == nil {
+			return false
+		}
+		return a.y < b.y
+	})
+	outgoing := map[int64][]models.SankeyLink{}
+	for _, link := range links {
+		outgoing[link.Source] = append(outgoing[link.Source], link)
+	}
+
+	// Boxes are in column order, top to bottom within each column
+	for _, src := range l.boxes {
+		for _, link := range outgoing[src.node.ID] {
+			dst := boxes[link.Target]
+			if dst == nil || link.Value <= 0 {
+				continue
+			}
+			t := link.Value * scale
+			l.bands = append(l.bands, band{
+				color:     link.Color,
+				x0:        src.x + nodeWidth,
+				x1:        dst.x,
+				y0:        src.y + src.outY,
+				y1:        dst.y + dst.inY,
+				thickness: t,
+			})
+			src.outY += t
+			dst.inY += t
+		}
+	}
+
+	return l
+}
+
+// label is the text drawn beside a node
+func (b *box) label() string {
+	return b.node.Label + "  " + FormatMoney(b.node.Value)
+}
+
+// FormatMoney formats dollars with thousands separators, dropping the cents
+// on whole amounts: $5,000 or $134.20
+func FormatMoney(v float64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	whole, cents, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if cents != "00" {
+		b.WriteString("." + cents)
+	}
+	return sign + "$" + b.String()
+}
+
+// ParseColor reads a #rrggbb or #rgb color
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+func luminance(c color.RGBA) float64 {
+	return (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+}
@@ -0,0 +1,72 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// fallbackColor replaces colors that don't parse, so user-entered colors
+// never reach the markup unchecked
+const fallbackColor = "#71717a"
+
+// SankeySVG renders a Sankey graph as a standalone SVG document
+func SankeySVG(graph models.SankeyResponse, opts Options) ([]byte, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	l := layoutSankey(graph, opts)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", safeColor(opts.Background))
+
+	y := float64(marginY)
+	if opts.Title != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" font-size="15" font-weight="bold" fill="%s">%s</text>`+"\n",
+			marginX, y+14, l.ink, html.EscapeString(opts.Title))
+		y += titleSpace
+	}
+	for i, c := range l.columns {
+		anchor, x := "start", c.x
+		if i == len(l.columns)-1 {
+			anchor, x = "end", c.x+nodeWidth
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" letter-spacing="1" fill="%s" opacity="0.7" text-anchor="%s">%s</text>`+"\n",
+			x, y+14, l.ink, anchor, html.EscapeString(c.name))
+	}
+
+	for _, d := range l.bands {
+		cx := (d.x0 + d.x1) / 2
+		fmt.Fprintf(&b, `<path d="M%.1f %.1f C%.1f %.1f %.1f %.1f %.1f %.1f L%.1f %.1f C%.1f %.1f %.1f %.1f %.1f %.1f Z" fill="%s" fill-opacity="%.2f"/>`+"\n",
+			d.x0, d.y0, cx, d.y0, cx, d.y1, d.x1, d.y1,
+			d.x1, d.y1+d.thickness, cx, d.y1+d.thickness, cx, d.y0+d.thickness, d.x0, d.y0+d.thickness,
+			safeColor(d.color), linkOpacity)
+	}
+
+	for _, n := range l.boxes {
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%.1f" fill="%s"/>`+"\n",
+			n.x, n.y, nodeWidth, n.h, safeColor(n.node.Color))
+
+		x, anchor := n.x+nodeWidth+labelPad, "start"
+		if !n.labelRight {
+			x, anchor = n.x-labelPad, "end"
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="12" fill="%s" text-anchor="%s" dominant-baseline="middle">%s</text>`+"\n",
+			x, n.y+n.h/2, l.ink, anchor, html.EscapeString(n.label()))
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Bytes(), nil
+}
+
+func safeColor(s string) string {
+	c, err := ParseColor(s)
+	if err != nil {
+		return fallbackColor
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
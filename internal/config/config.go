@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +14,44 @@ type Config struct {
 	RefreshExpiry   time.Duration
 	AllowedOrigins  string
 	BcryptCost      int
+	AdminEmails     []string
+
+	// Backups
+	BackupDir          string
+	BackupInterval     time.Duration // 0 disables scheduled backups
+	BackupKeepHourly   int
+	BackupKeepDaily    int
+	BackupKeepWeekly   int
+	BackupEncryptionKey string // base64 AES-256 key; empty uploads plaintext
+
+	// S3-compatible off-box backup target; empty endpoint disables uploads
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3Prefix    string
+
+	// Interest accrual for APY-bearing nodes
+	InterestInterval    time.Duration // How often the job runs; 0 disables
+	InterestCompounding string        // daily or monthly
+
+	// Balance snapshots for history charts; 0 disables
+	BalanceSnapshotInterval time.Duration
+
+	// Posting recurring flows into goal nodes as goal contributions; 0 disables
+	GoalContributionInterval time.Duration
+
+	// Paying due bills from sinking funds; 0 disables
+	SinkingFundInterval time.Duration
+
+	// Balance the cash-flow calendar warns about accounts dipping below
+	LowBalanceFloor float64
+
+	// Field-level encryption; an empty key stores sensitive text in plaintext
+	FieldEncryptionKey          string // base64 AES-256 key-encryption key
+	FieldEncryptionKeyFile      string // read when FieldEncryptionKey is empty
+	FieldEncryptionPreviousKeys []string
 }
 
 func Load() *Config {
@@ -22,6 +62,36 @@ func Load() *Config {
 		RefreshExpiry:   7 * 24 * time.Hour,
 		AllowedOrigins:  getEnv("ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:3000"),
 		BcryptCost:      12,
+		AdminEmails:     getEnvList("ADMIN_EMAILS"),
+
+		BackupDir:          getEnv("BACKUP_DIR", "./backups"),
+		BackupInterval:     getEnvDuration("BACKUP_INTERVAL", time.Hour),
+		BackupKeepHourly:   getEnvInt("BACKUP_KEEP_HOURLY", 24),
+		BackupKeepDaily:    getEnvInt("BACKUP_KEEP_DAILY", 7),
+		BackupKeepWeekly:   getEnvInt("BACKUP_KEEP_WEEKLY", 4),
+		BackupEncryptionKey: os.Getenv("BACKUP_ENCRYPTION_KEY"),
+
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3Prefix:    getEnv("S3_PREFIX", "vault-x/"),
+
+		InterestInterval:    getEnvDuration("INTEREST_INTERVAL", time.Hour),
+		InterestCompounding: getEnv("INTEREST_COMPOUNDING", "monthly"),
+
+		BalanceSnapshotInterval: getEnvDuration("BALANCE_SNAPSHOT_INTERVAL", 24*time.Hour),
+
+		GoalContributionInterval: getEnvDuration("GOAL_CONTRIBUTION_INTERVAL", time.Hour),
+
+		SinkingFundInterval: getEnvDuration("SINKING_FUND_INTERVAL", time.Hour),
+
+		LowBalanceFloor: getEnvFloat("LOW_BALANCE_FLOOR", 0),
+
+		FieldEncryptionKey:          os.Getenv("FIELD_ENCRYPTION_KEY"),
+		FieldEncryptionKeyFile:      os.Getenv("FIELD_ENCRYPTION_KEY_FILE"),
+		FieldEncryptionPreviousKeys: getEnvList("FIELD_ENCRYPTION_PREVIOUS_KEYS"),
 	}
 }
 
@@ -31,3 +101,35 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
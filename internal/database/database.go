@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Initialize applies any restore staged by the backup manager, then opens
+// the database. Only the server calls it, at startup, before anything else
+// has the file open.
 func Initialize(dbPath string) (*sql.DB, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(dbPath)
@@ -16,6 +20,17 @@ func Initialize(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
+	// Swap in a restore staged by the backup manager before anything opens the file
+	if err := applyPendingRestore(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to apply staged restore: %w", err)
+	}
+
+	return Open(dbPath)
+}
+
+// Open opens the database as it is, leaving any staged restore for the
+// server to apply. Command-line tools use it since the server may be running.
+func Open(dbPath string) (*sql.DB, error) {
 	// Open database with optimized settings for Pi
 	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=5000&_busy_timeout=5000")
 	if err != nil {
@@ -34,6 +49,33 @@ func Initialize(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// PendingRestorePath is where a verified snapshot waits to replace the database
+func PendingRestorePath(dbPath string) string {
+	return dbPath + ".restore"
+}
+
+func applyPendingRestore(dbPath string) error {
+	staged := PendingRestorePath(dbPath)
+	if _, err := os.Stat(staged); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Keep the replaced database (and any un-checkpointed WAL) alongside it
+	if _, err := os.Stat(dbPath); err == nil {
+		kept := fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().UTC().Format("20060102-150405"))
+		if err := os.Rename(dbPath, kept); err != nil {
+			return err
+		}
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if _, err := os.Stat(dbPath + suffix); err == nil {
+				os.Rename(dbPath+suffix, kept+suffix)
+			}
+		}
+	}
+
+	return os.Rename(staged, dbPath)
+}
+
 func Migrate(db *sql.DB) error {
 	migrations := []string{
 		// Users table
@@ -60,7 +102,7 @@ func Migrate(db *sql.DB) error {
 		`CREATE TABLE IF NOT EXISTS nodes (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			profile_id INTEGER NOT NULL,
-			type TEXT NOT NULL CHECK(type IN ('income', 'account', 'savings', 'investment', 'expense', 'budget', 'goal')),
+			type TEXT NOT NULL CHECK(type IN ('income', 'account', 'savings', 'investment', 'expense', 'budget', 'goal', 'liability', 'credit_card', 'loan')),
 			label TEXT NOT NULL,
 			institution TEXT,
 			amount REAL DEFAULT 0,
@@ -71,6 +113,11 @@ func Migrate(db *sql.DB) error {
 			metadata TEXT DEFAULT '{}',
 			sort_order INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			principal REAL DEFAULT 0,
+			interest_rate REAL DEFAULT 0,
+			term_months INTEGER DEFAULT 0,
+			minimum_payment REAL DEFAULT 0,
+			interest_accrued_through DATE,
 			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
 		)`,
 
@@ -84,6 +131,8 @@ func Migrate(db *sql.DB) error {
 			label TEXT,
 			is_recurring BOOLEAN DEFAULT 1,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			anchor_date DATE,
+			contributed_through DATE,
 			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE,
 			FOREIGN KEY (from_node_id) REFERENCES nodes(id) ON DELETE CASCADE,
 			FOREIGN KEY (to_node_id) REFERENCES nodes(id) ON DELETE CASCADE
@@ -109,6 +158,7 @@ func Migrate(db *sql.DB) error {
 			budget_id INTEGER NOT NULL,
 			amount REAL NOT NULL,
 			note TEXT,
+			payee TEXT,
 			date DATE NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE
@@ -125,6 +175,9 @@ func Migrate(db *sql.DB) error {
 			deadline DATE,
 			priority INTEGER DEFAULT 0,
 			color TEXT DEFAULT '#a855f7',
+			rollover INTEGER DEFAULT 0,
+			completed_at DATETIME,
+			archived_at DATETIME,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE,
 			FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE SET NULL
@@ -138,6 +191,19 @@ func Migrate(db *sql.DB) error {
 			note TEXT,
 			date DATE NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			flow_id INTEGER REFERENCES flows(id) ON DELETE SET NULL,
+			kind TEXT,
+			FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
+		)`,
+
+		// Goal milestones: a percentage of the target or a fixed amount
+		`CREATE TABLE IF NOT EXISTS goal_milestones (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			goal_id INTEGER NOT NULL,
+			percent REAL,
+			amount REAL,
+			reached_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
 		)`,
 
@@ -156,6 +222,120 @@ func Migrate(db *sql.DB) error {
 			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
 		)`,
 
+		// Sinking funds: a savings node set aside for one quarterly or annual expense
+		`CREATE TABLE IF NOT EXISTS sinking_funds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			profile_id INTEGER NOT NULL,
+			expense_id INTEGER NOT NULL UNIQUE,
+			node_id INTEGER NOT NULL UNIQUE,
+			last_paid DATE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE,
+			FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+			FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+		)`,
+
+		// Transactions flagged as unusual, one row per transaction and kind
+		`CREATE TABLE IF NOT EXISTS transaction_anomalies (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			profile_id INTEGER NOT NULL,
+			transaction_id INTEGER NOT NULL,
+			budget_id INTEGER NOT NULL,
+			kind TEXT NOT NULL CHECK(kind IN ('budget_amount', 'payee_amount', 'duplicate')),
+			score REAL DEFAULT 0,
+			median REAL DEFAULT 0,
+			related_transaction_id INTEGER,
+			status TEXT NOT NULL DEFAULT 'open' CHECK(status IN ('open', 'dismissed', 'confirmed')),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME,
+			UNIQUE (transaction_id, kind),
+			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE,
+			FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+		)`,
+
+		// Interest credited to APY-bearing nodes, one row per compounding period
+		`CREATE TABLE IF NOT EXISTS interest_accruals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node_id INTEGER NOT NULL,
+			amount REAL NOT NULL,
+			balance_after REAL NOT NULL,
+			apy REAL NOT NULL,
+			period_start DATE NOT NULL,
+			period_end DATE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (node_id, period_end),
+			FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+		)`,
+
+		// Balance snapshots for history and net-worth charts
+		`CREATE TABLE IF NOT EXISTS node_balance_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node_id INTEGER NOT NULL,
+			balance REAL NOT NULL,
+			source TEXT NOT NULL,
+			recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+		)`,
+
+		// Investment holdings; lots are derived from the transactions in FIFO order
+		`CREATE TABLE IF NOT EXISTS holdings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node_id INTEGER NOT NULL,
+			symbol TEXT NOT NULL,
+			asset_class TEXT NOT NULL DEFAULT 'stock',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (node_id, symbol),
+			FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS holding_transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			holding_id INTEGER NOT NULL,
+			type TEXT NOT NULL CHECK (type IN ('buy', 'sell', 'dividend')),
+			quantity REAL NOT NULL DEFAULT 0,
+			price REAL NOT NULL DEFAULT 0,
+			amount REAL NOT NULL,
+			date DATE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (holding_id) REFERENCES holdings(id) ON DELETE CASCADE
+		)`,
+
+		// Security prices, entered by hand or imported from CSV
+		`CREATE TABLE IF NOT EXISTS prices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			profile_id INTEGER NOT NULL,
+			symbol TEXT NOT NULL,
+			date DATE NOT NULL,
+			price REAL NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (profile_id, symbol, date),
+			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+		)`,
+
+		// What-if scenarios: overlays applied to a copy of a profile's nodes and flows
+		`CREATE TABLE IF NOT EXISTS scenarios (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			profile_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS scenario_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			scenario_id INTEGER NOT NULL,
+			kind TEXT NOT NULL CHECK (kind IN ('node_amount', 'flow_amount', 'add_flow', 'remove_flow')),
+			node_id INTEGER,
+			flow_id INTEGER,
+			from_node_id INTEGER,
+			to_node_id INTEGER,
+			amount REAL NOT NULL DEFAULT 0,
+			label TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (scenario_id) REFERENCES scenarios(id) ON DELETE CASCADE
+		)`,
+
 		// Refresh tokens table
 		`CREATE TABLE IF NOT EXISTS refresh_tokens (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -166,6 +346,15 @@ func Migrate(db *sql.DB) error {
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
 
+		// Data-encryption keys for field-level encryption, wrapped by the configured KEK
+		`CREATE TABLE IF NOT EXISTS encryption_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			wrapped_key TEXT NOT NULL,
+			kek_id TEXT NOT NULL,
+			active BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
 		// Indexes for performance
 		`CREATE INDEX IF NOT EXISTS idx_profiles_user ON profiles(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_nodes_profile ON nodes(profile_id)`,
@@ -173,10 +362,18 @@ func Migrate(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_budgets_profile ON budgets(profile_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_transactions_budget ON transactions(budget_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_transactions_date ON transactions(date)`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_budget_date ON transactions(budget_id, date)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_anomalies_profile ON transaction_anomalies(profile_id, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_goals_profile ON goals(profile_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_goal_transactions_goal ON goal_transactions(goal_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_goal_transactions_date ON goal_transactions(date)`,
+		`CREATE INDEX IF NOT EXISTS idx_goal_milestones_goal ON goal_milestones(goal_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_node_balance_history_node ON node_balance_history(node_id, recorded_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_holding_transactions_holding ON holding_transactions(holding_id, date)`,
 		`CREATE INDEX IF NOT EXISTS idx_expenses_profile ON expenses(profile_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_sinking_funds_profile ON sinking_funds(profile_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_scenarios_profile ON scenarios(profile_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_scenario_changes_scenario ON scenario_changes(scenario_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id)`,
 	}
 
@@ -187,7 +384,8 @@ func Migrate(db *sql.DB) error {
 	}
 
 	// Fix nodes table CHECK constraint if needed (migration for existing databases)
-	// This recreates the nodes table with the correct constraint including 'budget' and 'goal'
+	// This recreates the nodes table with the correct constraint including 'budget', 'goal'
+	// and the liability types
 	if err := migrateNodesTableConstraint(db); err != nil {
 		return fmt.Errorf("failed to migrate nodes table: %w", err)
 	}
@@ -202,11 +400,31 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to migrate goals table: %w", err)
 	}
 
+	// Add liability and interest columns to nodes table if missing
+	if err := migrateNodesColumns(db); err != nil {
+		return fmt.Errorf("failed to migrate nodes columns: %w", err)
+	}
+
+	// Add goal contribution scheduling columns if missing
+	if err := migrateGoalContributionColumns(db); err != nil {
+		return fmt.Errorf("failed to migrate goal contribution columns: %w", err)
+	}
+
+	// Add payee column to transactions table if missing
+	if err := addMissingColumns(db, "transactions", []column{{"payee", "TEXT"}}); err != nil {
+		return fmt.Errorf("failed to migrate transactions table: %w", err)
+	}
+
+	// Add goal lifecycle columns and default milestones if missing
+	if err := migrateGoalLifecycle(db); err != nil {
+		return fmt.Errorf("failed to migrate goal lifecycle: %w", err)
+	}
+
 	return nil
 }
 
 func migrateNodesTableConstraint(db *sql.DB) error {
-	// Check if migration is needed by trying to insert a test budget node
+	// Check if migration is needed by trying to insert a test loan node
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -214,7 +432,7 @@ func migrateNodesTableConstraint(db *sql.DB) error {
 	defer tx.Rollback()
 
 	// Try to detect if the old constraint exists
-	_, err = tx.Exec("INSERT INTO nodes (profile_id, type, label) VALUES (-1, 'budget', '__migration_test__')")
+	_, err = tx.Exec("INSERT INTO nodes (profile_id, type, label) VALUES (-1, 'loan', '__migration_test__')")
 	if err == nil {
 		// Constraint is already correct, clean up test row
 		tx.Exec("DELETE FROM nodes WHERE profile_id = -1 AND label = '__migration_test__'")
@@ -238,7 +456,7 @@ func migrateNodesTableConstraint(db *sql.DB) error {
 		CREATE TABLE nodes_new (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			profile_id INTEGER NOT NULL,
-			type TEXT NOT NULL CHECK(type IN ('income', 'account', 'savings', 'investment', 'expense', 'budget', 'goal')),
+			type TEXT NOT NULL CHECK(type IN ('income', 'account', 'savings', 'investment', 'expense', 'budget', 'goal', 'liability', 'credit_card', 'loan')),
 			label TEXT NOT NULL,
 			institution TEXT,
 			amount REAL DEFAULT 0,
@@ -252,8 +470,9 @@ func migrateNodesTableConstraint(db *sql.DB) error {
 			FOREIGN KEY (profile_id) REFERENCES profiles(id) ON DELETE CASCADE
 		);
 
-		-- Copy existing data
-		INSERT INTO nodes_new SELECT * FROM nodes;
+		-- Copy existing data (newer columns are added afterwards)
+		INSERT INTO nodes_new SELECT id, profile_id, type, label, institution, amount, balance, apy,
+			budgeted, goal, metadata, sort_order, created_at FROM nodes;
 
 		-- Drop old table
 		DROP TABLE nodes;
@@ -318,3 +537,86 @@ func migrateGoalsNodeID(db *sql.DB) error {
 	_, err = db.Exec("ALTER TABLE goals ADD COLUMN node_id INTEGER REFERENCES nodes(id) ON DELETE SET NULL")
 	return err
 }
+
+type column struct{ name, definition string }
+
+func migrateNodesColumns(db *sql.DB) error {
+	return addMissingColumns(db, "nodes", []column{
+		{"principal", "REAL DEFAULT 0"},
+		{"interest_rate", "REAL DEFAULT 0"},
+		{"term_months", "INTEGER DEFAULT 0"},
+		{"minimum_payment", "REAL DEFAULT 0"},
+		{"interest_accrued_through", "DATE"},
+	})
+}
+
+func migrateGoalContributionColumns(db *sql.DB) error {
+	if err := addMissingColumns(db, "flows", []column{
+		{"anchor_date", "DATE"},
+		{"contributed_through", "DATE"},
+	}); err != nil {
+		return err
+	}
+	if err := addMissingColumns(db, "goal_transactions", []column{
+		{"flow_id", "INTEGER REFERENCES flows(id) ON DELETE SET NULL"},
+		// NULL until the server classifies rows written before kinds existed
+		{"kind", "TEXT"},
+	}); err != nil {
+		return err
+	}
+
+	// A flow posts at most one contribution a day, even if the job overlaps
+	_, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_goal_transactions_flow ON goal_transactions(flow_id, date)")
+	return err
+}
+
+func migrateGoalLifecycle(db *sql.DB) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('goals') WHERE name='completed_at'").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := addMissingColumns(db, "goals", []column{
+		{"rollover", "INTEGER DEFAULT 0"},
+		{"completed_at", "DATETIME"},
+		{"archived_at", "DATETIME"},
+	}); err != nil {
+		return err
+	}
+
+	// Existing goals get the default milestones; those already passed
+	// count as reached now
+	_, err = db.Exec(`
+		INSERT INTO goal_milestones (goal_id, percent, reached_at)
+		SELECT g.id, p.percent,
+			CASE WHEN g.target > 0 AND g.current >= g.target * p.percent / 100 THEN CURRENT_TIMESTAMP END
+		FROM goals g, (SELECT 25 AS percent UNION ALL SELECT 50 UNION ALL SELECT 75 UNION ALL SELECT 100) p
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE goals SET completed_at = CURRENT_TIMESTAMP WHERE target > 0 AND current >= target")
+	return err
+}
+
+func addMissingColumns(db *sql.DB, table string, columns []column) error {
+	for _, col := range columns {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, col.name).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + col.name + " " + col.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,494 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encrypted values look like "enc:v1:<key id>:<base64 nonce|ciphertext>"
+const prefix = "enc:v1:"
+
+// Column is a text column holding sensitive data.
+// Amounts stay plaintext: balances and spend are aggregated in SQL.
+type Column struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+var Columns = []Column{
+	{"nodes", "label"},
+	{"nodes", "institution"},
+	{"flows", "label"},
+	{"budgets", "name"},
+	{"transactions", "note"},
+	{"transactions", "payee"},
+	{"goals", "name"},
+	{"goal_transactions", "note"},
+	{"expenses", "name"},
+	{"scenarios", "name"},
+	{"scenarios", "description"},
+	{"scenario_changes", "label"},
+}
+
+// Keyring holds the data-encryption keys (DEKs). DEKs are stored in the
+// encryption_keys table wrapped by the key-encryption key (KEK), which only
+// ever lives in config. A Keyring without a KEK passes values through.
+type Keyring struct {
+	mu     sync.RWMutex
+	kek    []byte
+	keys   map[int64]cipher.AEAD
+	active int64
+}
+
+// LoadKEK reads a base64 AES-256 key from the value, or from a file if the
+// value is empty. Both empty means encryption is disabled.
+func LoadKEK(value, file string) ([]byte, error) {
+	if value == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return parseKey(value)
+}
+
+// ParseKEKs parses a comma-separated list of retired KEKs
+func ParseKEKs(values []string) ([][]byte, error) {
+	keys := make([][]byte, 0, len(values))
+	for _, value := range values {
+		key, err := parseKey(value)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Open loads the DEKs. DEKs wrapped by a retired KEK are re-wrapped with the
+// current one, which is how the KEK is rotated. A DEK is created on first use.
+func Open(db *sql.DB, kek []byte, retired [][]byte) (*Keyring, error) {
+	k := &Keyring{kek: kek, keys: map[int64]cipher.AEAD{}}
+
+	if kek == nil {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM encryption_keys").Scan(&count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("database has encrypted fields but FIELD_ENCRYPTION_KEY is not set")
+		}
+		return k, nil
+	}
+
+	keks := map[string][]byte{kekID(kek): kek}
+	for _, old := range retired {
+		keks[kekID(old)] = old
+	}
+
+	type row struct {
+		id      int64
+		wrapped string
+		kekID   string
+		active  bool
+	}
+	rows, err := db.Query("SELECT id, wrapped_key, kek_id, active FROM encryption_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	var stored []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.wrapped, &r.kekID, &r.active); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stored = append(stored, r)
+	}
+	rows.Close()
+
+	for _, r := range stored {
+		wrapping, ok := keks[r.kekID]
+		if !ok {
+			return nil, fmt.Errorf("data key %d is wrapped by an unknown key (add it to FIELD_ENCRYPTION_PREVIOUS_KEYS)", r.id)
+		}
+		dek, err := unwrap(wrapping, r.wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key %d: %w", r.id, err)
+		}
+
+		if r.kekID != kekID(kek) {
+			rewrapped, err := wrap(kek, dek)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := db.Exec("UPDATE encryption_keys SET wrapped_key = ?, kek_id = ? WHERE id = ?", rewrapped, kekID(kek), r.id); err != nil {
+				return nil, err
+			}
+		}
+
+		if k.keys[r.id], err = newAEAD(dek); err != nil {
+			return nil, err
+		}
+		if r.active {
+			k.active = r.id
+		}
+	}
+
+	if k.active == 0 {
+		if err := k.addKey(db); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+func (k *Keyring) Enabled() bool {
+	return k.kek != nil
+}
+
+func (k *Keyring) ActiveKey() int64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Encrypt seals a value with the active DEK. Empty strings stay empty so
+// "keep the existing value" checks in SQL keep working.
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	if !k.Enabled() || plain == "" {
+		return plain, nil
+	}
+
+	k.mu.RLock()
+	id, aead := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+
+	return prefix + strconv.FormatInt(id, 10) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens an encrypted value; anything without the prefix is returned
+// unchanged so rows written before encryption was enabled still read.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+
+	rest := strings.TrimPrefix(value, prefix)
+	sep := strings.IndexByte(rest, ':')
+	if sep < 0 {
+		return "", errors.New("malformed encrypted value")
+	}
+	id, err := strconv.ParseInt(rest[:sep], 10, 64)
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown data key %d", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(rest[sep+1:])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value")
+	}
+
+	return string(plain), nil
+}
+
+// EncryptAll encrypts values in place, stopping at the first failure
+func (k *Keyring) EncryptAll(values ...*string) error {
+	for _, v := range values {
+		sealed, err := k.Encrypt(*v)
+		if err != nil {
+			return err
+		}
+		*v = sealed
+	}
+	return nil
+}
+
+// DecryptAll decrypts values in place, stopping at the first failure
+func (k *Keyring) DecryptAll(values ...*string) error {
+	for _, v := range values {
+		plain, err := k.Decrypt(*v)
+		if err != nil {
+			return err
+		}
+		*v = plain
+	}
+	return nil
+}
+
+// EncryptExisting rewrites every sensitive value not already sealed with the
+// active DEK. Used as the in-place migration and after rotation.
+func (k *Keyring) EncryptExisting(db *sql.DB) (int, error) {
+	if !k.Enabled() {
+		return 0, nil
+	}
+
+	current := prefix + strconv.FormatInt(k.ActiveKey(), 10) + ":"
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, col := range Columns {
+		type pending struct {
+			id    int64
+			value string
+		}
+		var stale []pending
+
+		rows, err := tx.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IS NOT NULL AND %s != ''", col.Column, col.Table, col.Column, col.Column))
+		if err != nil {
+			return updated, err
+		}
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.value); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			if !strings.HasPrefix(p.value, current) {
+				stale = append(stale, p)
+			}
+		}
+		rows.Close()
+
+		for _, p := range stale {
+			plain, err := k.Decrypt(p.value)
+			if err != nil {
+				return updated, fmt.Errorf("%s.%s row %d: %w", col.Table, col.Column, p.id, err)
+			}
+			sealed, err := k.Encrypt(plain)
+			if err != nil {
+				return updated, err
+			}
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", col.Table, col.Column), sealed, p.id); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+
+	return updated, tx.Commit()
+}
+
+// Rotate creates a new active DEK, re-encrypts every value with it and
+// drops the retired DEKs no value still uses. A request that sealed a value
+// with the old DEK just before the switch can write it after the rewrite
+// has passed its row, so the rewrite runs a second time and any DEK still
+// in use afterwards is kept until PruneKeys finds it unused. Returns the
+// number of values rewritten and the retired DEKs kept.
+func (k *Keyring) Rotate(db *sql.DB) (int, int, error) {
+	if !k.Enabled() {
+		return 0, 0, errors.New("field encryption is not enabled")
+	}
+
+	if err := k.addKey(db); err != nil {
+		return 0, 0, err
+	}
+
+	updated := 0
+	for pass := 0; pass < 2; pass++ {
+		n, err := k.EncryptExisting(db)
+		updated += n
+		if err != nil {
+			return updated, 0, err
+		}
+	}
+
+	kept, err := k.PruneKeys(db)
+	return updated, kept, err
+}
+
+// PruneKeys deletes the retired DEKs that no stored value uses and returns
+// how many are kept. Each DEK is deleted before its values are counted, in
+// one transaction: the delete takes SQLite's write lock, so no value sealed
+// with it can be written between the count and the commit.
+func (k *Keyring) PruneKeys(db *sql.DB) (int, error) {
+	if !k.Enabled() {
+		return 0, nil
+	}
+
+	active := k.ActiveKey()
+	k.mu.RLock()
+	var retired []int64
+	for id := range k.keys {
+		if id != active {
+			retired = append(retired, id)
+		}
+	}
+	k.mu.RUnlock()
+
+	kept := 0
+	for _, id := range retired {
+		inUse, err := k.dropKey(db, id)
+		if err != nil {
+			return kept, err
+		}
+		if inUse {
+			kept++
+			continue
+		}
+		k.mu.Lock()
+		delete(k.keys, id)
+		k.mu.Unlock()
+	}
+
+	return kept, nil
+}
+
+// dropKey deletes a DEK unless some value is still sealed with it, and
+// reports whether it was kept
+func (k *Keyring) dropKey(db *sql.DB, id int64) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM encryption_keys WHERE id = ? AND active = 0", id); err != nil {
+		return false, err
+	}
+
+	pattern := prefix + strconv.FormatInt(id, 10) + ":%"
+	for _, col := range Columns {
+		var found int
+		err := tx.QueryRow(fmt.Sprintf("SELECT 1 FROM %s WHERE %s LIKE ? LIMIT 1", col.Table, col.Column), pattern).Scan(&found)
+		if err == nil {
+			return true, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+	}
+
+	return false, tx.Commit()
+}
+
+// addKey generates a DEK, stores it wrapped and makes it active
+func (k *Keyring) addKey(db *sql.DB) error {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return err
+	}
+	wrapped, err := wrap(k.kek, dek)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE encryption_keys SET active = 0"); err != nil {
+		return err
+	}
+	result, err := tx.Exec("INSERT INTO encryption_keys (wrapped_key, kek_id, active) VALUES (?, ?, 1)", wrapped, kekID(k.kek))
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys[id] = aead
+	k.active = id
+	k.mu.Unlock()
+
+	return nil
+}
+
+func wrap(kek, dek []byte) (string, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, dek, nil)), nil
+}
+
+func unwrap(kek []byte, wrapped string) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed wrapped key")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+// kekID fingerprints a KEK so wrapped DEKs record which KEK sealed them
+func kekID(kek []byte) string {
+	sum := sha256.Sum256(kek)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func parseKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
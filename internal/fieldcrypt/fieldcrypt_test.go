@@ -0,0 +1,315 @@
+package fieldcrypt
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thejoshbq/vault-x/internal/database"
+)
+
+var memoryDBs atomic.Int64
+
+// newTestDB opens a migrated in-memory database. A single connection keeps
+// every query on the same memory database.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:fieldcrypt%d?mode=memory&cache=shared", memoryDBs.Add(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newKEK(t *testing.T) []byte {
+	t.Helper()
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		t.Fatal(err)
+	}
+	return kek
+}
+
+func mustOpen(t *testing.T, db *sql.DB, kek []byte, retired ...[]byte) *Keyring {
+	t.Helper()
+	k, err := Open(db, kek, retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func mustEncrypt(t *testing.T, k *Keyring, plain string) string {
+	t.Helper()
+	sealed, err := k.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func insertLabel(t *testing.T, db *sql.DB, label string) int64 {
+	t.Helper()
+	result, err := db.Exec("INSERT INTO nodes (profile_id, type, label) VALUES (1, 'account', ?)", label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+func label(t *testing.T, db *sql.DB, id int64) string {
+	t.Helper()
+	var l string
+	if err := db.QueryRow("SELECT label FROM nodes WHERE id = ?", id).Scan(&l); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	db := newTestDB(t)
+	k := mustOpen(t, db, newKEK(t))
+
+	sealed := mustEncrypt(t, k, "Chase checking")
+	want := prefix + strconv.FormatInt(k.ActiveKey(), 10) + ":"
+	if !strings.HasPrefix(sealed, want) {
+		t.Errorf("sealed = %q, want prefix %q", sealed, want)
+	}
+	if strings.Contains(sealed, "Chase") {
+		t.Errorf("sealed value %q contains the plaintext", sealed)
+	}
+	if again := mustEncrypt(t, k, "Chase checking"); again == sealed {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "sealed", value: sealed, want: "Chase checking"},
+		{name: "plaintext passes through", value: "Written before encryption", want: "Written before encryption"},
+		{name: "empty passes through", value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.Decrypt(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if e := mustEncrypt(t, k, ""); e != "" {
+		t.Errorf("Encrypt(\"\") = %q, want empty", e)
+	}
+
+	for _, bad := range []string{
+		prefix + "x:AAAA",
+		prefix + "99:" + sealed[len(want):],
+		prefix + "nokey",
+		sealed[:len(sealed)-4] + "AAAA",
+	} {
+		if _, err := k.Decrypt(bad); err == nil {
+			t.Errorf("Decrypt(%q) succeeded", bad)
+		}
+	}
+
+	a, b := "Groceries", "Rent"
+	if err := k.EncryptAll(&a, &b); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.DecryptAll(&a, &b); err != nil {
+		t.Fatal(err)
+	}
+	if a != "Groceries" || b != "Rent" {
+		t.Errorf("EncryptAll/DecryptAll round trip = %q, %q", a, b)
+	}
+}
+
+func TestDisabledKeyring(t *testing.T) {
+	db := newTestDB(t)
+	k := mustOpen(t, db, nil)
+	if k.Enabled() {
+		t.Fatal("keyring without a KEK is enabled")
+	}
+	if sealed := mustEncrypt(t, k, "plain"); sealed != "plain" {
+		t.Errorf("Encrypt = %q, want plain", sealed)
+	}
+	if n, err := k.EncryptExisting(db); err != nil || n != 0 {
+		t.Errorf("EncryptExisting = %d, %v; want 0, nil", n, err)
+	}
+
+	// Once a data key exists the KEK can't be dropped
+	mustOpen(t, db, newKEK(t))
+	if _, err := Open(db, nil, nil); err == nil {
+		t.Error("opened encrypted database without a KEK")
+	}
+}
+
+func TestOpenRewrapsUnderNewKEK(t *testing.T) {
+	db := newTestDB(t)
+	retired, current := newKEK(t), newKEK(t)
+
+	k := mustOpen(t, db, retired)
+	sealed := mustEncrypt(t, k, "Brokerage")
+
+	if _, err := Open(db, current, nil); err == nil {
+		t.Fatal("opened with a KEK that didn't wrap the data key")
+	}
+
+	k = mustOpen(t, db, current, retired)
+	if plain, err := k.Decrypt(sealed); err != nil || plain != "Brokerage" {
+		t.Fatalf("Decrypt after KEK rotation = %q, %v", plain, err)
+	}
+	var wrappedBy string
+	if err := db.QueryRow("SELECT kek_id FROM encryption_keys").Scan(&wrappedBy); err != nil {
+		t.Fatal(err)
+	}
+	if wrappedBy != kekID(current) {
+		t.Errorf("data key wrapped by %s, want %s", wrappedBy, kekID(current))
+	}
+
+	// The retired KEK is no longer needed
+	k = mustOpen(t, db, current)
+	if plain, err := k.Decrypt(sealed); err != nil || plain != "Brokerage" {
+		t.Errorf("Decrypt without the retired KEK = %q, %v", plain, err)
+	}
+	if _, err := Open(db, retired, nil); err == nil {
+		t.Error("opened with the retired KEK alone")
+	}
+}
+
+func TestEncryptExisting(t *testing.T) {
+	db := newTestDB(t)
+	plain := insertLabel(t, db, "Savings")
+	empty := insertLabel(t, db, "")
+	if _, err := db.Exec("INSERT INTO transactions (budget_id, amount, note, payee, date) VALUES (1, 10, 'Lunch', NULL, '2026-10-01')"); err != nil {
+		t.Fatal(err)
+	}
+
+	k := mustOpen(t, db, newKEK(t))
+	n, err := k.EncryptExisting(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("encrypted %d values, want 2", n)
+	}
+
+	sealed := label(t, db, plain)
+	if !strings.HasPrefix(sealed, prefix) {
+		t.Errorf("label = %q, want it encrypted", sealed)
+	}
+	if got, err := k.Decrypt(sealed); err != nil || got != "Savings" {
+		t.Errorf("Decrypt = %q, %v; want Savings", got, err)
+	}
+	if l := label(t, db, empty); l != "" {
+		t.Errorf("empty label = %q, want it left empty", l)
+	}
+	var note string
+	var payee sql.NullString
+	if err := db.QueryRow("SELECT note, payee FROM transactions").Scan(&note, &payee); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := k.Decrypt(note); got != "Lunch" || payee.Valid {
+		t.Errorf("note %q, payee %v; want Lunch, NULL", got, payee)
+	}
+
+	if n, err := k.EncryptExisting(db); err != nil || n != 0 {
+		t.Errorf("second run encrypted %d (%v), want 0", n, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	db := newTestDB(t)
+	k := mustOpen(t, db, newKEK(t))
+	first := k.ActiveKey()
+	id := insertLabel(t, db, mustEncrypt(t, k, "Emergency fund"))
+	plainID := insertLabel(t, db, "Written before encryption")
+
+	updated, kept, err := k.Rotate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 2 || kept != 0 {
+		t.Errorf("Rotate = %d updated, %d kept; want 2, 0", updated, kept)
+	}
+	if k.ActiveKey() == first {
+		t.Fatal("active key unchanged")
+	}
+	current := prefix + strconv.FormatInt(k.ActiveKey(), 10) + ":"
+	for _, row := range []int64{id, plainID} {
+		if l := label(t, db, row); !strings.HasPrefix(l, current) {
+			t.Errorf("node %d label %q not sealed with the new key", row, l)
+		}
+	}
+	var keys int
+	if err := db.QueryRow("SELECT COUNT(*) FROM encryption_keys").Scan(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys != 1 {
+		t.Errorf("%d data keys stored, want 1", keys)
+	}
+	if got, err := k.Decrypt(label(t, db, id)); err != nil || got != "Emergency fund" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+}
+
+func TestPruneKeysKeepsKeysInUse(t *testing.T) {
+	db := newTestDB(t)
+	kek := newKEK(t)
+	k := mustOpen(t, db, kek)
+
+	// A value sealed with the old key lands after the rewrite has passed it
+	stale := mustEncrypt(t, k, "Roth IRA")
+	if err := k.addKey(db); err != nil {
+		t.Fatal(err)
+	}
+	id := insertLabel(t, db, stale)
+
+	kept, err := k.PruneKeys(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != 1 {
+		t.Fatalf("kept %d keys, want 1", kept)
+	}
+	if got, err := k.Decrypt(label(t, db, id)); err != nil || got != "Roth IRA" {
+		t.Errorf("Decrypt with the retained key = %q, %v", got, err)
+	}
+	// It survives a restart too
+	if got, err := mustOpen(t, db, kek).Decrypt(label(t, db, id)); err != nil || got != "Roth IRA" {
+		t.Errorf("Decrypt after reopening = %q, %v", got, err)
+	}
+
+	if _, err := k.EncryptExisting(db); err != nil {
+		t.Fatal(err)
+	}
+	if kept, err := k.PruneKeys(db); err != nil || kept != 0 {
+		t.Errorf("PruneKeys once unused = %d kept, %v; want 0", kept, err)
+	}
+	var keys int
+	if err := db.QueryRow("SELECT COUNT(*) FROM encryption_keys").Scan(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys != 1 {
+		t.Errorf("%d data keys stored, want 1", keys)
+	}
+	if got, err := k.Decrypt(label(t, db, id)); err != nil || got != "Roth IRA" {
+		t.Errorf("Decrypt after pruning = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/backup"
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// ============================================
+// ADMIN HANDLERS
+// ============================================
+
+func (h *Handler) ListBackups(c *fiber.Ctx) error {
+	snapshots, err := h.backups.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list backups"})
+	}
+
+	response := fiber.Map{
+		"directory": h.backups.Dir(),
+		"backups":   snapshots,
+	}
+
+	if h.backups.HasRemote() {
+		remote, err := h.backups.ListRemote(c.Context())
+		if err != nil {
+			response["remote_error"] = err.Error()
+		} else {
+			response["remote"] = remote
+		}
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) CreateBackup(c *fiber.Ctx) error {
+	snapshot, err := h.backups.Create(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(snapshot)
+}
+
+// VerifyBackup re-runs the integrity check against an existing snapshot
+func (h *Handler) VerifyBackup(c *fiber.Ctx) error {
+	path, err := h.backups.Path(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "backup not found"})
+	}
+
+	if err := backup.Verify(path); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"name": c.Params("name"), "ok": false, "error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"name": c.Params("name"), "ok": true})
+}
+
+// RestoreBackup stages a snapshot (by name, or the newest at or before "at")
+// to replace the database on the next restart
+func (h *Handler) RestoreBackup(c *fiber.Ctx) error {
+	var req models.RestoreBackupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	restore := backup.RestoreRequest{Name: req.Name, Remote: req.Source == "remote"}
+	if req.At != "" {
+		at, err := time.Parse(time.RFC3339, req.At)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "at must be an RFC3339 timestamp"})
+		}
+		restore.At = at
+	}
+
+	snapshot, err := h.backups.StageRestore(c.Context(), restore)
+	if errors.Is(err, backup.ErrNoSnapshot) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"staged":           snapshot,
+		"restart_required": true,
+	})
+}
+
+func (h *Handler) EncryptionStatus(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"enabled":    h.crypt.Enabled(),
+		"active_key": h.crypt.ActiveKey(),
+		"columns":    fieldcrypt.Columns,
+	})
+}
+
+// RotateEncryptionKey creates a new data key and re-encrypts every
+// sensitive value with it. Rotating the key-encryption key itself is done
+// by changing FIELD_ENCRYPTION_KEY and restarting.
+func (h *Handler) RotateEncryptionKey(c *fiber.Ctx) error {
+	if !h.crypt.Enabled() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "field encryption is not enabled"})
+	}
+
+	updated, kept, err := h.crypt.Rotate(h.db)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"active_key":    h.crypt.ActiveKey(),
+		"reencrypted":   updated,
+		"retained_keys": kept, // Retired keys still in use; dropped on a later rotation or restart
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// ANOMALY HANDLERS
+// ============================================
+
+// ListAnomalies returns the profile's flagged transactions, newest first.
+// Query: status (open by default, dismissed, confirmed or all).
+func (h *Handler) ListAnomalies(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	status := c.Query("status", services.AnomalyOpen)
+	switch status {
+	case "all":
+		status = ""
+	case services.AnomalyOpen, services.AnomalyDismissed, services.AnomalyConfirmed:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status must be open, dismissed, confirmed or all"})
+	}
+
+	anomalies, err := h.store.Anomalies(profileID, status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load anomalies"})
+	}
+
+	return c.JSON(anomalies)
+}
+
+// DismissAnomaly marks a flagged transaction as normal spending
+func (h *Handler) DismissAnomaly(c *fiber.Ctx) error {
+	return h.resolveAnomaly(c, services.AnomalyDismissed)
+}
+
+// ConfirmAnomaly marks a flagged transaction as genuinely unusual, which
+// keeps it out of the history later transactions are scored against
+func (h *Handler) ConfirmAnomaly(c *fiber.Ctx) error {
+	return h.resolveAnomaly(c, services.AnomalyConfirmed)
+}
+
+func (h *Handler) resolveAnomaly(c *fiber.Ctx, status string) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	anomalyID, _ := strconv.ParseInt(c.Params("anomalyId"), 10, 64)
+	err = h.store.ResolveAnomaly(profileID, anomalyID, status)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "anomaly not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update anomaly"})
+	}
+
+	return c.JSON(fiber.Map{"id": anomalyID, "status": status})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// CASH-FLOW HANDLERS
+// ============================================
+
+// GetCashFlow returns each account's projected balance day by day, marking
+// paydays and bills, with warnings for days below the floor. Query: days
+// (default 90) and floor (default LOW_BALANCE_FLOOR).
+func (h *Handler) GetCashFlow(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	days := c.QueryInt("days", 90)
+	if days < 1 || days > services.MaxCashFlowDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "days must be between 1 and 366"})
+	}
+	floor := c.QueryFloat("floor", h.cfg.LowBalanceFloor)
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.BuildCashFlowCalendar(data, time.Now(), days, floor))
+}
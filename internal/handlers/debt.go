@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// DEBT HANDLERS
+// ============================================
+
+// GetAmortization returns the payment schedule for a liability node.
+// Query: payment (override), start (first payment date), extra_monthly and
+// any number of lump_sum=YYYY-MM-DD:amount.
+func (h *Handler) GetAmortization(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	nodeID, err := strconv.ParseInt(c.Params("nodeId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid node ID"})
+	}
+
+	node, err := h.store.Node(profileID, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if !services.IsLiability(node.Type) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "node is not a liability"})
+	}
+
+	payment := services.ScheduledPayment(node)
+	if v := c.Query("payment"); v != "" {
+		if payment, err = strconv.ParseFloat(v, 64); err != nil || payment <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment must be a positive number"})
+		}
+	}
+
+	now := time.Now()
+	first := services.AddMonths(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), 1)
+	if v := c.Query("start"); v != "" {
+		if first, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start must be YYYY-MM-DD"})
+		}
+	}
+
+	extra, err := parseExtraPayments(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp := models.AmortizationResponse{
+		NodeID:       node.ID,
+		Balance:      node.Balance,
+		InterestRate: node.InterestRate,
+		Payment:      payment,
+	}
+
+	resp.Schedule, err = services.Amortize(node.Balance, node.InterestRate, payment, first, services.ExtraPayments{})
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !extra.Empty() {
+		withExtra, err := services.Amortize(node.Balance, node.InterestRate, payment, first, extra)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		resp.WithExtra = &withExtra
+		resp.InterestSaved = services.RoundCents(resp.Schedule.TotalInterest - withExtra.TotalInterest)
+		resp.MonthsSaved = resp.Schedule.Months - withExtra.Months
+	}
+
+	return c.JSON(resp)
+}
+
+func parseExtraPayments(c *fiber.Ctx) (services.ExtraPayments, error) {
+	var extra services.ExtraPayments
+
+	if v := c.Query("extra_monthly"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil || amount < 0 {
+			return extra, errors.New("extra_monthly must be a non-negative number")
+		}
+		extra.Monthly = amount
+	}
+
+	for _, raw := range c.Context().QueryArgs().PeekMulti("lump_sum") {
+		date, amount, ok := strings.Cut(string(raw), ":")
+		if !ok {
+			return extra, errors.New("lump_sum must be YYYY-MM-DD:amount")
+		}
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return extra, errors.New("lump_sum must be YYYY-MM-DD:amount")
+		}
+		value, err := strconv.ParseFloat(amount, 64)
+		if err != nil || value <= 0 {
+			return extra, errors.New("lump_sum amount must be a positive number")
+		}
+		extra.LumpSums = append(extra.LumpSums, services.LumpSum{Date: t, Amount: value})
+	}
+
+	return extra, nil
+}
+
+// GetDebtPlan compares payoff plans for every liability in the profile.
+// Query: strategy (snowball, avalanche, custom; default all), budget
+// (monthly amount, default what currently goes to debts), order (comma-separated
+// node IDs for custom).
+func (h *Handler) GetDebtPlan(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	data, err := h.store.Load(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := models.DebtPlanResponse{MonthlyBudget: services.DebtBudget(data), Plans: []models.DebtPlan{}}
+	for _, n := range data.Nodes {
+		if services.IsLiability(n.Type) && n.Balance > 0 {
+			resp.TotalDebt += n.Balance
+			resp.MinimumPayments += services.ScheduledPayment(n)
+		}
+	}
+	resp.MinimumPayments = services.RoundCents(resp.MinimumPayments)
+
+	if v := c.Query("budget"); v != "" {
+		if resp.MonthlyBudget, err = strconv.ParseFloat(v, 64); err != nil || resp.MonthlyBudget <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "budget must be a positive number"})
+		}
+	} else if resp.MonthlyBudget < resp.MinimumPayments {
+		// Flows into a debt can be below its minimum; plan on the minimums
+		resp.MonthlyBudget = resp.MinimumPayments
+	}
+
+	var order []int64
+	for _, part := range strings.Split(c.Query("order"), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "order must be comma-separated node IDs"})
+		}
+		order = append(order, id)
+	}
+
+	strategies := []string{services.StrategySnowball, services.StrategyAvalanche}
+	if len(order) > 0 {
+		strategies = append(strategies, services.StrategyCustom)
+	}
+	if v := c.Query("strategy"); v != "" {
+		strategies = []string{v}
+	}
+
+	now := time.Now()
+	first := services.AddMonths(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), 1)
+
+	for _, strategy := range strategies {
+		plan, err := services.PlanDebtPayoff(data.Nodes, resp.MonthlyBudget, strategy, order, first)
+		if errors.Is(err, services.ErrBudgetBelowMinimums) || errors.Is(err, services.ErrNeverPaysOff) || errors.Is(err, services.ErrScheduleTooLong) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		resp.Plans = append(resp.Plans, plan)
+	}
+
+	best := -1
+	for i, plan := range resp.Plans {
+		if plan.Months > 0 && (best < 0 || plan.TotalInterest < resp.Plans[best].TotalInterest) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		resp.Recommended = resp.Plans[best].Strategy
+	}
+
+	return c.JSON(resp)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// EXPORT HANDLERS
+// ============================================
+
+// ExportLedger renders the profile as an hledger or beancount journal
+func (h *Handler) ExportLedger(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	format := services.LedgerFormat(c.Query("format", string(services.LedgerHledger)))
+	if !format.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be hledger or beancount"})
+	}
+
+	currency := strings.ToUpper(c.Query("currency", "USD"))
+	if !isCommodity(currency) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid currency"})
+	}
+
+	data, err := h.store.Load(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteLedger(&buf, data, format, currency); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to render ledger"})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="vault-x-profile-%d.%s"`, profileID, format.Extension()))
+	return c.Send(buf.Bytes())
+}
+
+// isCommodity accepts beancount-style commodity names like USD or VTSAX
+func isCommodity(s string) bool {
+	if len(s) < 2 || len(s) > 24 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s[0] >= 'A' && s[0] <= 'Z'
+}
+
+// ExportUser returns a versioned JSON archive of every profile the user owns
+func (h *Handler) ExportUser(c *fiber.Ctx) error {
+	userID := h.getUserID(c)
+
+	archive, err := h.store.ExportUser(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to export data"})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="vault-x-backup-%s.json"`, archive.ExportedAt.Format("20060102-150405")))
+	return c.JSON(archive)
+}
+
+// ImportUser restores an archive produced by ExportUser.
+// ?dry_run=true reports what would be created and any conflicts without writing.
+func (h *Handler) ImportUser(c *fiber.Ctx) error {
+	userID := h.getUserID(c)
+
+	var archive services.UserArchive
+	if err := c.BodyParser(&archive); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	opts := services.ImportOptions{
+		DryRun:     c.QueryBool("dry_run", false),
+		OnConflict: c.Query("on_conflict", services.ImportSkip),
+	}
+
+	report, err := h.store.ImportUser(userID, &archive, opts)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if report.DryRun {
+		return c.JSON(report)
+	}
+	return c.Status(fiber.StatusCreated).JSON(report)
+}
@@ -5,25 +5,41 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/thejoshbq/vault-x/internal/backup"
 	"github.com/thejoshbq/vault-x/internal/config"
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
 	"github.com/thejoshbq/vault-x/internal/middleware"
 	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
 )
 
 type Handler struct {
-	db  *sql.DB
-	cfg *config.Config
+	db      *sql.DB
+	cfg     *config.Config
+	store   *services.Store
+	backups *backup.Manager
+	crypt   *fieldcrypt.Keyring
 }
 
-func New(db *sql.DB, cfg *config.Config) *Handler {
-	return &Handler{db: db, cfg: cfg}
+func New(db *sql.DB, cfg *config.Config, backups *backup.Manager, crypt *fieldcrypt.Keyring) *Handler {
+	return &Handler{
+		db:      db,
+		cfg:     cfg,
+		store:   services.NewStore(db, crypt),
+		backups: backups,
+		crypt:   crypt,
+	}
 }
 
 // Helper to get user ID from context
@@ -341,7 +357,8 @@ func (h *Handler) ListNodes(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.db.Query(`
-		SELECT id, profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata, sort_order, created_at
+		SELECT id, profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata, sort_order, created_at,
+			principal, interest_rate, term_months, minimum_payment
 		FROM nodes WHERE profile_id = ? ORDER BY sort_order, created_at
 	`, profileID)
 	if err != nil {
@@ -353,9 +370,13 @@ func (h *Handler) ListNodes(c *fiber.Ctx) error {
 	for rows.Next() {
 		var n models.Node
 		var institution, metadata sql.NullString
-		rows.Scan(&n.ID, &n.ProfileID, &n.Type, &n.Label, &institution, &n.Amount, &n.Balance, &n.APY, &n.Budgeted, &n.Goal, &metadata, &n.SortOrder, &n.CreatedAt)
+		rows.Scan(&n.ID, &n.ProfileID, &n.Type, &n.Label, &institution, &n.Amount, &n.Balance, &n.APY, &n.Budgeted, &n.Goal, &metadata, &n.SortOrder, &n.CreatedAt,
+			&n.Principal, &n.InterestRate, &n.TermMonths, &n.MinimumPayment)
 		n.Institution = institution.String
 		n.Metadata = metadata.String
+		if err := h.crypt.DecryptAll(&n.Label, &n.Institution); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decrypt data"})
+		}
 		nodes = append(nodes, n)
 	}
 
@@ -375,14 +396,24 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 
 	// Validate type
 	validTypes := map[string]bool{"income": true, "account": true, "savings": true, "investment": true, "expense": true, "budget": true}
-	if !validTypes[req.Type] {
+	if !validTypes[req.Type] && !services.IsLiability(req.Type) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid node type"})
 	}
+	if req.Principal < 0 || req.InterestRate < 0 || req.TermMonths < 0 || req.MinimumPayment < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "liability terms cannot be negative"})
+	}
+
+	label, institution := req.Label, req.Institution
+	if err := h.crypt.EncryptAll(&label, &institution); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
 
 	result, err := h.db.Exec(`
-		INSERT INTO nodes (profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, profileID, req.Type, req.Label, req.Institution, req.Amount, req.Balance, req.APY, req.Budgeted, req.Goal, req.Metadata)
+		INSERT INTO nodes (profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata,
+			principal, interest_rate, term_months, minimum_payment)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, profileID, req.Type, label, institution, req.Amount, req.Balance, req.APY, req.Budgeted, req.Goal, req.Metadata,
+		req.Principal, req.InterestRate, req.TermMonths, req.MinimumPayment)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("failed to create node: %v", err)})
@@ -393,6 +424,12 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get node ID"})
 	}
 
+	if services.HasBalance(req.Type) {
+		if err := services.RecordBalance(h.db, id, req.Balance, services.BalanceCreated); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to record balance"})
+		}
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(models.Node{
 		ID:          id,
 		ProfileID:   profileID,
@@ -406,6 +443,10 @@ func (h *Handler) CreateNode(c *fiber.Ctx) error {
 		Goal:        req.Goal,
 		Metadata:    req.Metadata,
 		CreatedAt:   time.Now(),
+		Principal:      req.Principal,
+		InterestRate:   req.InterestRate,
+		TermMonths:     req.TermMonths,
+		MinimumPayment: req.MinimumPayment,
 	})
 }
 
@@ -425,7 +466,42 @@ func (h *Handler) UpdateNode(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	_, err = h.db.Exec(`
+	if req.Principal < 0 || req.InterestRate < 0 || req.TermMonths < 0 || req.MinimumPayment < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "liability terms cannot be negative"})
+	}
+
+	// A goal's node is edited through its goal so the two stay in step
+	err = h.store.UpdateGoalNode(profileID, nodeID, req)
+	if err == nil {
+		return c.JSON(fiber.Map{"id": nodeID, "updated": true})
+	}
+	if err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update node"})
+	}
+
+	label, institution := req.Label, req.Institution
+	if err := h.crypt.EncryptAll(&label, &institution); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	defer tx.Rollback()
+
+	// Snapshot the balance only when it actually changes
+	var nodeType string
+	var oldBalance float64
+	err = tx.QueryRow("SELECT type, balance FROM nodes WHERE id = ? AND profile_id = ?", nodeID, profileID).Scan(&nodeType, &oldBalance)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	_, err = tx.Exec(`
 		UPDATE nodes SET 
 			label = COALESCE(NULLIF(?, ''), label),
 			institution = ?,
@@ -434,14 +510,29 @@ func (h *Handler) UpdateNode(c *fiber.Ctx) error {
 			apy = ?,
 			budgeted = ?,
 			goal = ?,
-			metadata = COALESCE(NULLIF(?, ''), metadata)
+			metadata = COALESCE(NULLIF(?, ''), metadata),
+			principal = ?,
+			interest_rate = ?,
+			term_months = ?,
+			minimum_payment = ?
 		WHERE id = ? AND profile_id = ?
-	`, req.Label, req.Institution, req.Amount, req.Balance, req.APY, req.Budgeted, req.Goal, req.Metadata, nodeID, profileID)
+	`, label, institution, req.Amount, req.Balance, req.APY, req.Budgeted, req.Goal, req.Metadata,
+		req.Principal, req.InterestRate, req.TermMonths, req.MinimumPayment, nodeID, profileID)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update node"})
 	}
 
+	if services.HasBalance(nodeType) && req.Balance != oldBalance {
+		if err := services.RecordBalance(tx, nodeID, req.Balance, services.BalanceUpdated); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to record balance"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update node"})
+	}
+
 	return c.JSON(fiber.Map{"id": nodeID, "updated": true})
 }
 
@@ -453,17 +544,36 @@ func (h *Handler) DeleteNode(c *fiber.Ctx) error {
 
 	nodeID, _ := strconv.ParseInt(c.Params("nodeId"), 10, 64)
 
-	// Delete associated flows first
-	h.db.Exec("DELETE FROM flows WHERE from_node_id = ? OR to_node_id = ?", nodeID, nodeID)
-
-	_, err = h.db.Exec("DELETE FROM nodes WHERE id = ? AND profile_id = ?", nodeID, profileID)
-	if err != nil {
+	if err := h.store.DeleteNode(profileID, nodeID); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete node"})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// ListInterestAccruals returns the interest credited to a node, newest first
+func (h *Handler) ListInterestAccruals(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	nodeID, _ := strconv.ParseInt(c.Params("nodeId"), 10, 64)
+	if _, err := h.store.Node(profileID, nodeID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	}
+
+	accruals, err := h.store.InterestAccruals(nodeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(accruals)
+}
+
 // ============================================
 // FLOW HANDLERS (Sankey)
 // ============================================
@@ -475,7 +585,7 @@ func (h *Handler) ListFlows(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.db.Query(`
-		SELECT id, profile_id, from_node_id, to_node_id, amount, label, is_recurring, created_at
+		SELECT id, profile_id, from_node_id, to_node_id, amount, label, is_recurring, anchor_date, created_at
 		FROM flows WHERE profile_id = ?
 	`, profileID)
 	if err != nil {
@@ -486,9 +596,13 @@ func (h *Handler) ListFlows(c *fiber.Ctx) error {
 	flows := []models.Flow{}
 	for rows.Next() {
 		var f models.Flow
-		var label sql.NullString
-		rows.Scan(&f.ID, &f.ProfileID, &f.FromNodeID, &f.ToNodeID, &f.Amount, &label, &f.IsRecurring, &f.CreatedAt)
+		var label, anchor sql.NullString
+		rows.Scan(&f.ID, &f.ProfileID, &f.FromNodeID, &f.ToNodeID, &f.Amount, &label, &f.IsRecurring, &anchor, &f.CreatedAt)
 		f.Label = label.String
+		f.AnchorDate = services.DateOnly(anchor.String)
+		if err := h.crypt.DecryptAll(&f.Label); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decrypt data"})
+		}
 		flows = append(flows, f)
 	}
 
@@ -506,10 +620,29 @@ func (h *Handler) CreateFlow(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
+	if req.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "flow amount must be positive"})
+	}
+	if _, err := time.Parse("2006-01-02", req.AnchorDate); req.AnchorDate != "" && err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "anchor_date must be YYYY-MM-DD"})
+	}
+	err = h.store.CheckFlowEndpoints(profileID, req.FromNodeID, req.ToNodeID)
+	if errors.Is(err, services.ErrSelfLoop) || errors.Is(err, services.ErrFlowNodeNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	label := req.Label
+	if err := h.crypt.EncryptAll(&label); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
 	result, err := h.db.Exec(`
 		INSERT INTO flows (profile_id, from_node_id, to_node_id, amount, label, is_recurring)
 		VALUES (?, ?, ?, ?, ?, ?)
-	`, profileID, req.FromNodeID, req.ToNodeID, req.Amount, req.Label, req.IsRecurring)
+	`, profileID, req.FromNodeID, req.ToNodeID, req.Amount, label, req.IsRecurring)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create flow"})
@@ -519,6 +652,11 @@ func (h *Handler) CreateFlow(c *fiber.Ctx) error {
 	if err != nil || id == 0 {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get flow ID"})
 	}
+	if req.AnchorDate != "" {
+		if err := services.AnchorFlow(h.db, id, req.AnchorDate, time.Now()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to schedule flow"})
+		}
+	}
 	return c.Status(fiber.StatusCreated).JSON(models.Flow{
 		ID:          id,
 		ProfileID:   profileID,
@@ -527,6 +665,7 @@ func (h *Handler) CreateFlow(c *fiber.Ctx) error {
 		Amount:      req.Amount,
 		Label:       req.Label,
 		IsRecurring: req.IsRecurring,
+		AnchorDate:  req.AnchorDate,
 		CreatedAt:   time.Now(),
 	})
 }
@@ -544,15 +683,34 @@ func (h *Handler) UpdateFlow(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	_, err = h.db.Exec(`
+	if req.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "flow amount must be positive"})
+	}
+	if _, err := time.Parse("2006-01-02", req.AnchorDate); req.AnchorDate != "" && err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "anchor_date must be YYYY-MM-DD"})
+	}
+
+	label := req.Label
+	if err := h.crypt.EncryptAll(&label); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
+	result, err := h.db.Exec(`
 		UPDATE flows SET amount = ?, label = ?, is_recurring = ?
 		WHERE id = ? AND profile_id = ?
-	`, req.Amount, req.Label, req.IsRecurring, flowID, profileID)
+	`, req.Amount, label, req.IsRecurring, flowID, profileID)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update flow"})
 	}
 
+	// Leaving anchor_date out keeps the flow's schedule
+	if n, _ := result.RowsAffected(); n > 0 && req.AnchorDate != "" {
+		if err := services.AnchorFlow(h.db, flowID, req.AnchorDate, time.Now()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to schedule flow"})
+		}
+	}
+
 	return c.JSON(fiber.Map{"id": flowID, "updated": true})
 }
 
@@ -572,6 +730,21 @@ func (h *Handler) DeleteFlow(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// ValidateFlows reports conservation problems and cycles in the flow graph
+func (h *Handler) ValidateFlows(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	data, err := h.store.Load(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(services.ValidateFlows(data))
+}
+
 // ============================================
 // BUDGET & TRANSACTION HANDLERS
 // ============================================
@@ -582,42 +755,21 @@ func (h *Handler) ListBudgets(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Get current month boundaries
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	monthEnd := monthStart.AddDate(0, 1, 0)
-
-	rows, err := h.db.Query(`
-		SELECT b.id, b.profile_id, b.node_id, b.name, b.budgeted, b.period, b.color, b.created_at,
-			COALESCE(SUM(t.amount), 0) as spent
-		FROM budgets b
-		LEFT JOIN transactions t ON b.id = t.budget_id
-			AND t.date >= ? AND t.date < ?
-		WHERE b.profile_id = ?
-		GROUP BY b.id
-		ORDER BY b.name
-	`, monthStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"), profileID)
-
+	budgets, err := h.store.Budgets(profileID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
-	defer rows.Close()
-
-	budgets := []models.Budget{}
-	for rows.Next() {
-		var b models.Budget
-		var nodeID sql.NullInt64
-		rows.Scan(&b.ID, &b.ProfileID, &nodeID, &b.Name, &b.Budgeted, &b.Period, &b.Color, &b.CreatedAt, &b.Spent)
-		if nodeID.Valid {
-			b.NodeID = nodeID.Int64
-		}
-		b.Remaining = b.Budgeted - b.Spent
-		if b.Budgeted > 0 {
-			b.Percentage = (b.Spent / b.Budgeted) * 100
-		}
-		budgets = append(budgets, b)
+	transactions, err := h.store.Transactions(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
+	// Spending and pace over each budget's own period
+	budgets = services.AssessBudgets(budgets, transactions, time.Now())
+
+	// Names may be encrypted at rest, so sort after decrypting
+	sort.SliceStable(budgets, func(i, j int) bool { return budgets[i].Name < budgets[j].Name })
+
 	return c.JSON(budgets)
 }
 
@@ -639,10 +791,15 @@ func (h *Handler) CreateBudget(c *fiber.Ctx) error {
 		req.Color = "#10b981"
 	}
 
+	name := req.Name
+	if err := h.crypt.EncryptAll(&name); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
 	result, err := h.db.Exec(`
 		INSERT INTO budgets (profile_id, node_id, name, budgeted, period, color)
 		VALUES (?, ?, ?, ?, ?, ?)
-	`, profileID, req.NodeID, req.Name, req.Budgeted, req.Period, req.Color)
+	`, profileID, req.NodeID, name, req.Budgeted, req.Period, req.Color)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("failed to create budget: %v", err)})
@@ -677,10 +834,15 @@ func (h *Handler) UpdateBudget(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
+	name := req.Name
+	if err := h.crypt.EncryptAll(&name); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
 	_, err = h.db.Exec(`
 		UPDATE budgets SET name = ?, budgeted = ?, period = ?, color = ?
 		WHERE id = ? AND profile_id = ?
-	`, req.Name, req.Budgeted, req.Period, req.Color, budgetID, profileID)
+	`, name, req.Budgeted, req.Period, req.Color, budgetID, profileID)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update budget"})
@@ -701,6 +863,7 @@ func (h *Handler) DeleteBudget(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete budget"})
 	}
+	h.db.Exec("DELETE FROM transaction_anomalies WHERE budget_id = ? AND profile_id = ?", budgetID, profileID)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -721,7 +884,7 @@ func (h *Handler) ListTransactions(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.db.Query(`
-		SELECT id, budget_id, amount, note, date, created_at
+		SELECT id, budget_id, amount, note, payee, date, created_at
 		FROM transactions WHERE budget_id = ?
 		ORDER BY date DESC, created_at DESC
 		LIMIT 100
@@ -734,9 +897,12 @@ func (h *Handler) ListTransactions(c *fiber.Ctx) error {
 	transactions := []models.Transaction{}
 	for rows.Next() {
 		var t models.Transaction
-		var note sql.NullString
-		rows.Scan(&t.ID, &t.BudgetID, &t.Amount, &note, &t.Date, &t.CreatedAt)
-		t.Note = note.String
+		var note, payee sql.NullString
+		rows.Scan(&t.ID, &t.BudgetID, &t.Amount, &note, &payee, &t.Date, &t.CreatedAt)
+		t.Note, t.Payee = note.String, payee.String
+		if err := h.crypt.DecryptAll(&t.Note, &t.Payee); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decrypt data"})
+		}
 		transactions = append(transactions, t)
 	}
 
@@ -767,10 +933,20 @@ func (h *Handler) CreateTransaction(c *fiber.Ctx) error {
 		req.Date = time.Now().Format("2006-01-02")
 	}
 
+	req.Payee = strings.TrimSpace(req.Payee)
+	note, payee := req.Note, req.Payee
+	if err := h.crypt.EncryptAll(&note, &payee); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+	var payeeValue interface{}
+	if payee != "" {
+		payeeValue = payee
+	}
+
 	result, err := h.db.Exec(`
-		INSERT INTO transactions (budget_id, amount, note, date)
-		VALUES (?, ?, ?, ?)
-	`, budgetID, req.Amount, req.Note, req.Date)
+		INSERT INTO transactions (budget_id, amount, note, payee, date)
+		VALUES (?, ?, ?, ?, ?)
+	`, budgetID, req.Amount, note, payeeValue, req.Date)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create transaction"})
@@ -780,13 +956,22 @@ func (h *Handler) CreateTransaction(c *fiber.Ctx) error {
 	if err != nil || id == 0 {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get transaction ID"})
 	}
+
+	// The transaction is saved either way; a failed check just flags nothing
+	anomalies, err := h.store.DetectAnomalies(profileID, id)
+	if err != nil {
+		log.Printf("anomaly check for transaction %d failed: %v", id, err)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(models.Transaction{
 		ID:        id,
 		BudgetID:  budgetID,
 		Amount:    req.Amount,
 		Note:      req.Note,
+		Payee:     req.Payee,
 		Date:      req.Date,
 		CreatedAt: time.Now(),
+		Anomalies: anomalies,
 	})
 }
 
@@ -806,10 +991,17 @@ func (h *Handler) DeleteTransaction(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "budget not found"})
 	}
 
-	_, err = h.db.Exec("DELETE FROM transactions WHERE id = ? AND budget_id = ?", txID, budgetID)
+	result, err := h.db.Exec("DELETE FROM transactions WHERE id = ? AND budget_id = ?", txID, budgetID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete transaction"})
 	}
+	// Its own flags go with it, and so do duplicate flags on charges that repeated it
+	if n, _ := result.RowsAffected(); n > 0 {
+		h.db.Exec(`
+			DELETE FROM transaction_anomalies
+			WHERE profile_id = ? AND (transaction_id = ? OR (kind = ? AND related_transaction_id = ?))
+		`, profileID, txID, services.AnomalyDuplicate, txID)
+	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -818,49 +1010,23 @@ func (h *Handler) DeleteTransaction(c *fiber.Ctx) error {
 // GOAL HANDLERS
 // ============================================
 
+// ListGoals returns the profile's goals with their progress, projected
+// completion and a priority-ordered share of the monthly surplus
 func (h *Handler) ListGoals(c *fiber.Ctx) error {
 	profileID, err := h.getProfileID(c)
 	if err != nil {
 		return err
 	}
 
-	rows, err := h.db.Query(`
-		SELECT id, profile_id, node_id, name, target, current, deadline, priority, color, created_at
-		FROM goals WHERE profile_id = ?
-		ORDER BY priority, deadline
-	`, profileID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
 	}
-	defer rows.Close()
-
-	goals := []models.Goal{}
-	for rows.Next() {
-		var g models.Goal
-		var deadline sql.NullString
-		var nodeID sql.NullInt64
-		rows.Scan(&g.ID, &g.ProfileID, &nodeID, &g.Name, &g.Target, &g.Current, &deadline, &g.Priority, &g.Color, &g.CreatedAt)
-		g.Deadline = deadline.String
-		if nodeID.Valid {
-			g.NodeID = nodeID.Int64
-		}
-
-		// Compute derived fields
-		if g.Target > 0 {
-			g.Percentage = (g.Current / g.Target) * 100
-		}
-		if g.Deadline != "" {
-			if deadlineDate, err := time.Parse("2006-01-02", g.Deadline); err == nil {
-				g.DaysRemaining = int(time.Until(deadlineDate).Hours() / 24)
-				if g.DaysRemaining > 0 {
-					g.MonthlyNeeded = (g.Target - g.Current) / (float64(g.DaysRemaining) / 30)
-				}
-			}
-		}
 
-		goals = append(goals, g)
+	goals := services.AssessGoals(data, time.Now())
+	if c.Query("archived") != "true" {
+		goals = services.ActiveGoals(goals)
 	}
-
 	return c.JSON(goals)
 }
 
@@ -879,6 +1045,11 @@ func (h *Handler) CreateGoal(c *fiber.Ctx) error {
 		req.Color = "#a855f7"
 	}
 
+	name := req.Name
+	if err := h.crypt.EncryptAll(&name); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
 	// Start transaction to create both goal and node
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -886,11 +1057,12 @@ func (h *Handler) CreateGoal(c *fiber.Ctx) error {
 	}
 	defer tx.Rollback()
 
-	// Create corresponding node for the goal
+	// Create corresponding node for the goal; the opening balance is
+	// added below as a contribution so both sides move together
 	nodeResult, err := tx.Exec(`
 		INSERT INTO nodes (profile_id, type, label, balance, goal)
-		VALUES (?, 'goal', ?, ?, ?)
-	`, profileID, req.Name, req.Current, req.Target)
+		VALUES (?, 'goal', ?, 0, ?)
+	`, profileID, name, req.Target)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create node"})
 	}
@@ -901,22 +1073,39 @@ func (h *Handler) CreateGoal(c *fiber.Ctx) error {
 
 	// Create goal
 	result, err := tx.Exec(`
-		INSERT INTO goals (profile_id, node_id, name, target, current, deadline, priority, color)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, profileID, nodeID, req.Name, req.Target, req.Current, req.Deadline, req.Priority, req.Color)
+		INSERT INTO goals (profile_id, node_id, name, target, current, deadline, priority, color, rollover)
+		VALUES (?, ?, ?, ?, 0, ?, ?, ?, ?)
+	`, profileID, nodeID, name, req.Target, req.Deadline, req.Priority, req.Color, req.Rollover)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create goal"})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to commit transaction"})
-	}
-
 	id, err := result.LastInsertId()
 	if err != nil || id == 0 {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get goal ID"})
 	}
+
+	if err := services.AddDefaultMilestones(tx, id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create milestones"})
+	}
+
+	if req.Current != 0 {
+		_, err := h.store.ApplyGoalContribution(tx, models.GoalTransaction{
+			GoalID: id,
+			Amount: req.Current,
+			Note:   "Opening balance",
+			Date:   time.Now().Format("2006-01-02"),
+			Kind:   services.GoalTxOpening,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to record opening balance"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to commit transaction"})
+	}
 	return c.Status(fiber.StatusCreated).JSON(models.Goal{
 		ID:        id,
 		ProfileID: profileID,
@@ -927,6 +1116,7 @@ func (h *Handler) CreateGoal(c *fiber.Ctx) error {
 		Deadline:  req.Deadline,
 		Priority:  req.Priority,
 		Color:     req.Color,
+		Rollover:  req.Rollover,
 		CreatedAt: time.Now(),
 	})
 }
@@ -944,11 +1134,12 @@ func (h *Handler) UpdateGoal(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	_, err = h.db.Exec(`
-		UPDATE goals SET name = ?, target = ?, current = ?, deadline = ?, priority = ?, color = ?
-		WHERE id = ? AND profile_id = ?
-	`, req.Name, req.Target, req.Current, req.Deadline, req.Priority, req.Color, goalID, profileID)
-
+	// The goal's node follows its name and target, and a changed current
+	// amount is recorded as an adjustment
+	err = h.store.UpdateGoal(profileID, goalID, req)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "goal not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update goal"})
 	}
@@ -964,7 +1155,11 @@ func (h *Handler) DeleteGoal(c *fiber.Ctx) error {
 
 	goalID, _ := strconv.ParseInt(c.Params("goalId"), 10, 64)
 
-	_, err = h.db.Exec("DELETE FROM goals WHERE id = ? AND profile_id = ?", goalID, profileID)
+	// Takes the goal's transactions, node and flows with it
+	err = h.store.DeleteGoal(profileID, goalID)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "goal not found"})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete goal"})
 	}
@@ -992,7 +1187,7 @@ func (h *Handler) ListGoalTransactions(c *fiber.Ctx) error {
 	}
 
 	rows, err := h.db.Query(`
-		SELECT id, goal_id, amount, note, date, created_at
+		SELECT id, goal_id, amount, note, date, created_at, COALESCE(flow_id, 0), COALESCE(kind, 'contribution')
 		FROM goal_transactions WHERE goal_id = ?
 		ORDER BY date DESC, created_at DESC
 		LIMIT 100
@@ -1005,7 +1200,11 @@ func (h *Handler) ListGoalTransactions(c *fiber.Ctx) error {
 	transactions := []models.GoalTransaction{}
 	for rows.Next() {
 		var tx models.GoalTransaction
-		rows.Scan(&tx.ID, &tx.GoalID, &tx.Amount, &tx.Note, &tx.Date, &tx.CreatedAt)
+		rows.Scan(&tx.ID, &tx.GoalID, &tx.Amount, &tx.Note, &tx.Date, &tx.CreatedAt, &tx.FlowID, &tx.Kind)
+		tx.Date = services.DateOnly(tx.Date)
+		if err := h.crypt.DecryptAll(&tx.Note); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to decrypt data"})
+		}
 		transactions = append(transactions, tx)
 	}
 
@@ -1036,47 +1235,17 @@ func (h *Handler) CreateGoalTransaction(c *fiber.Ctx) error {
 		req.Date = time.Now().Format("2006-01-02")
 	}
 
-	// Start transaction to update both tables
-	tx, err := h.db.Begin()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
-	}
-	defer tx.Rollback()
-
-	// Insert transaction
-	result, err := tx.Exec(`
-		INSERT INTO goal_transactions (goal_id, amount, note, date)
-		VALUES (?, ?, ?, ?)
-	`, goalID, req.Amount, req.Note, req.Date)
+	t, err := h.store.AddGoalContribution(models.GoalTransaction{
+		GoalID: goalID,
+		Amount: req.Amount,
+		Note:   req.Note,
+		Date:   req.Date,
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create transaction"})
 	}
 
-	// Update goal's current amount
-	_, err = tx.Exec(`
-		UPDATE goals SET current = current + ?
-		WHERE id = ?
-	`, req.Amount, goalID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update goal"})
-	}
-
-	if err := tx.Commit(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to commit transaction"})
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil || id == 0 {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get goal transaction ID"})
-	}
-	return c.Status(fiber.StatusCreated).JSON(models.GoalTransaction{
-		ID:       id,
-		GoalID:   goalID,
-		Amount:   req.Amount,
-		Note:     req.Note,
-		Date:     req.Date,
-		CreatedAt: time.Now(),
-	})
+	return c.Status(fiber.StatusCreated).JSON(t)
 }
 
 func (h *Handler) DeleteGoalTransaction(c *fiber.Ctx) error {
@@ -1095,39 +1264,14 @@ func (h *Handler) DeleteGoalTransaction(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "goal not found"})
 	}
 
-	// Get transaction amount before deleting
-	var amount float64
-	err = h.db.QueryRow("SELECT amount FROM goal_transactions WHERE id = ? AND goal_id = ?", txID, goalID).Scan(&amount)
-	if err != nil {
+	err = h.store.DeleteGoalContribution(goalID, txID)
+	if err == sql.ErrNoRows {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "transaction not found"})
 	}
-
-	// Start transaction to update both tables
-	tx, err := h.db.Begin()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
-	}
-	defer tx.Rollback()
-
-	// Delete transaction
-	_, err = tx.Exec("DELETE FROM goal_transactions WHERE id = ? AND goal_id = ?", txID, goalID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete transaction"})
 	}
 
-	// Update goal's current amount (subtract the deleted transaction)
-	_, err = tx.Exec(`
-		UPDATE goals SET current = current - ?
-		WHERE id = ?
-	`, amount, goalID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update goal"})
-	}
-
-	if err := tx.Commit(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to commit transaction"})
-	}
-
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -1141,12 +1285,12 @@ func (h *Handler) GetDashboard(c *fiber.Ctx) error {
 		return err
 	}
 
-	// This would aggregate data from multiple tables
-	// For now, return a simple structure
-	return c.JSON(fiber.Map{
-		"profile_id": profileID,
-		"message":    "Dashboard data - implement aggregation logic",
-	})
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.BuildDashboard(data, time.Now()))
 }
 
 func (h *Handler) GetForecast(c *fiber.Ctx) error {
@@ -1155,10 +1299,17 @@ func (h *Handler) GetForecast(c *fiber.Ctx) error {
 		return err
 	}
 
-	return c.JSON(fiber.Map{
-		"profile_id": profileID,
-		"message":    "Forecast data - implement projection logic",
-	})
+	months := c.QueryInt("months", 12)
+	if months < 1 || months > 120 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "months must be between 1 and 120"})
+	}
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.BuildForecast(data, time.Now(), months))
 }
 
 // ============================================
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// BALANCE HISTORY HANDLERS
+// ============================================
+
+// GetNodeHistory returns a node's balance at the end of each period.
+// Query: granularity (daily, weekly, monthly), from and to (YYYY-MM-DD).
+func (h *Handler) GetNodeHistory(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	nodeID, err := strconv.ParseInt(c.Params("nodeId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid node ID"})
+	}
+
+	g, from, to, msg := parseSeriesRange(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	_, err = h.store.Node(profileID, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	points, err := h.store.NodeHistory(profileID, nodeID, g, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"node_id":     nodeID,
+		"granularity": g,
+		"from":        from.Format("2006-01-02"),
+		"to":          to.Format("2006-01-02"),
+		"points":      points,
+	})
+}
+
+// GetNetWorthHistory returns assets, liabilities and net worth at the end of
+// each period. Query: granularity, from and to as for GetNodeHistory.
+func (h *Handler) GetNetWorthHistory(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	g, from, to, msg := parseSeriesRange(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	points, err := h.store.NetWorthHistory(profileID, g, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"granularity": g,
+		"from":        from.Format("2006-01-02"),
+		"to":          to.Format("2006-01-02"),
+		"points":      points,
+	})
+}
+
+// parseSeriesRange reads granularity, from and to, defaulting to daily up to
+// today. A non-empty message means the query was invalid.
+func parseSeriesRange(c *fiber.Ctx) (services.Granularity, time.Time, time.Time, string) {
+	g := services.Granularity(c.Query("granularity", string(services.Daily)))
+	if !g.Valid() {
+		return g, time.Time{}, time.Time{}, "granularity must be daily, weekly or monthly"
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return g, time.Time{}, time.Time{}, "to must be YYYY-MM-DD"
+		}
+		to = t
+	}
+
+	from := g.DefaultFrom(to)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return g, time.Time{}, time.Time{}, "from must be YYYY-MM-DD"
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return g, time.Time{}, time.Time{}, "from must be on or before to"
+	}
+	return g, from, to, ""
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// INVESTMENT HANDLERS
+// ============================================
+
+// investmentNode loads a node and checks it can hold securities. It writes
+// the error response itself; a nil node means the caller should return.
+func (h *Handler) investmentNode(c *fiber.Ctx, profileID int64) (*models.Node, error) {
+	nodeID, err := strconv.ParseInt(c.Params("nodeId"), 10, 64)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid node ID"})
+	}
+
+	node, err := h.store.Node(profileID, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "node not found"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if node.Type != "investment" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "node is not an investment account"})
+	}
+
+	return &node, nil
+}
+
+// ListHoldings returns a node's holdings with their open lots and valuation
+func (h *Handler) ListHoldings(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	node, err := h.investmentNode(c, profileID)
+	if node == nil {
+		return err
+	}
+
+	holdings, err := h.store.Holdings(profileID, node.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(holdings)
+}
+
+func (h *Handler) CreateHolding(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	node, err := h.investmentNode(c, profileID)
+	if node == nil {
+		return err
+	}
+
+	var req models.CreateHoldingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	symbol := services.NormalizeSymbol(req.Symbol)
+	if symbol == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "symbol is required"})
+	}
+	if req.AssetClass == "" {
+		req.AssetClass = "stock"
+	}
+	if !services.ValidAssetClass(req.AssetClass) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "asset_class must be one of " + strings.Join(services.AssetClasses, ", ")})
+	}
+
+	var exists bool
+	h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM holdings WHERE node_id = ? AND symbol = ?)", node.ID, symbol).Scan(&exists)
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "node already holds " + symbol})
+	}
+
+	result, err := h.db.Exec("INSERT INTO holdings (node_id, symbol, asset_class) VALUES (?, ?, ?)", node.ID, symbol, req.AssetClass)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create holding"})
+	}
+
+	id, _ := result.LastInsertId()
+	return c.Status(fiber.StatusCreated).JSON(models.Holding{
+		ID:         id,
+		NodeID:     node.ID,
+		Symbol:     symbol,
+		AssetClass: req.AssetClass,
+		CreatedAt:  time.Now(),
+		Lots:       []models.Lot{},
+	})
+}
+
+func (h *Handler) DeleteHolding(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	holdingID, _ := strconv.ParseInt(c.Params("holdingId"), 10, 64)
+	err = h.store.DeleteHolding(profileID, holdingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "holding not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete holding"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) ListHoldingTransactions(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	holdingID, _ := strconv.ParseInt(c.Params("holdingId"), 10, 64)
+	if _, err := h.store.HoldingNode(profileID, holdingID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "holding not found"})
+	}
+
+	txs, err := h.store.HoldingTransactions(holdingID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(txs)
+}
+
+// CreateHoldingTransaction records a buy, sell or dividend. The node's
+// balance follows the holdings' market value.
+func (h *Handler) CreateHoldingTransaction(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	holdingID, _ := strconv.ParseInt(c.Params("holdingId"), 10, 64)
+
+	var req models.CreateHoldingTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	t, err := services.NewHoldingTransaction(holdingID, req, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	t, err = h.store.AddHoldingTransaction(profileID, t)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "holding not found"})
+	case errors.Is(err, services.ErrOversold):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to record transaction"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(t)
+}
+
+func (h *Handler) DeleteHoldingTransaction(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	holdingID, _ := strconv.ParseInt(c.Params("holdingId"), 10, 64)
+	txID, _ := strconv.ParseInt(c.Params("txId"), 10, 64)
+
+	err = h.store.DeleteHoldingTransaction(profileID, holdingID, txID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "transaction not found"})
+	case errors.Is(err, services.ErrOversold):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "later sells depend on this transaction: " + err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete transaction"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListPrices returns a symbol's price history, newest first
+func (h *Handler) ListPrices(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	prices, err := h.store.Prices(profileID, c.Params("symbol"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(prices)
+}
+
+// SetPrice enters one price by hand, replacing any for the same day
+func (h *Handler) SetPrice(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	var p models.Price
+	if err := c.BodyParser(&p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if p.Date == "" {
+		p.Date = time.Now().Format("2006-01-02")
+	}
+	if err := services.ValidatePrice(&p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.store.SetPrices(profileID, []models.Price{p}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save price"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(p)
+}
+
+// ImportPrices loads symbol,date,price rows from a CSV request body or a
+// multipart "file" upload. The whole file is rejected if any row is invalid.
+func (h *Handler) ImportPrices(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "failed to read upload"})
+		}
+		defer f.Close()
+		body = f
+	}
+
+	prices, err := services.ParsePriceCSV(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.store.SetPrices(profileID, prices); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save prices"})
+	}
+
+	return c.JSON(fiber.Map{"imported": len(prices)})
+}
+
+// GetInvestmentSummary totals every holding in the profile with gains and
+// allocation by asset class
+func (h *Handler) GetInvestmentSummary(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	summary, err := h.store.InvestmentSummary(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(summary)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// GOAL MILESTONE HANDLERS
+// ============================================
+
+// goal loads the goal named in the route with its milestones. It writes the
+// error response itself; a nil goal means the caller should return.
+func (h *Handler) goal(c *fiber.Ctx, profileID int64) (*models.Goal, error) {
+	goalID, err := strconv.ParseInt(c.Params("goalId"), 10, 64)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid goal ID"})
+	}
+
+	goals, err := h.store.Goals(profileID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	for _, g := range goals {
+		if g.ID == goalID {
+			return &g, nil
+		}
+	}
+
+	return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "goal not found"})
+}
+
+func (h *Handler) ListGoalMilestones(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.goal(c, profileID)
+	if g == nil {
+		return err
+	}
+
+	return c.JSON(g.Milestones)
+}
+
+// CreateGoalMilestone adds a milestone at a percentage of the target or at
+// a fixed amount. Body: percent or amount.
+func (h *Handler) CreateGoalMilestone(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.goal(c, profileID)
+	if g == nil {
+		return err
+	}
+
+	var req models.CreateGoalMilestoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if (req.Percent > 0) == (req.Amount > 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "give either a positive percent or a positive amount"})
+	}
+
+	id, err := h.store.AddGoalMilestone(g.ID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create milestone"})
+	}
+
+	milestones, err := h.store.GoalMilestones(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	for _, m := range milestones {
+		if m.ID == id {
+			return c.Status(fiber.StatusCreated).JSON(m)
+		}
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create milestone"})
+}
+
+func (h *Handler) DeleteGoalMilestone(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.goal(c, profileID)
+	if g == nil {
+		return err
+	}
+
+	milestoneID, _ := strconv.ParseInt(c.Params("milestoneId"), 10, 64)
+	result, err := h.db.Exec("DELETE FROM goal_milestones WHERE id = ? AND goal_id = ?", milestoneID, g.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete milestone"})
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "milestone not found"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetGoalHistory returns when the goal was created, reached each milestone,
+// was completed and was archived, oldest first
+func (h *Handler) GetGoalHistory(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.goal(c, profileID)
+	if g == nil {
+		return err
+	}
+
+	return c.JSON(services.GoalHistory(*g))
+}
+
+func (h *Handler) ArchiveGoal(c *fiber.Ctx) error {
+	return h.setGoalArchived(c, true)
+}
+
+func (h *Handler) UnarchiveGoal(c *fiber.Ctx) error {
+	return h.setGoalArchived(c, false)
+}
+
+func (h *Handler) setGoalArchived(c *fiber.Ctx, archive bool) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	goalID, _ := strconv.ParseInt(c.Params("goalId"), 10, 64)
+	err = h.store.ArchiveGoal(profileID, goalID, archive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "goal not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update goal"})
+	}
+
+	if !archive {
+		// Money that arrived while archived may now roll over
+		if err := h.store.SettleGoal(goalID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update goal"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"id": goalID, "archived": archive})
+}
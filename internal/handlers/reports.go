@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// REPORT HANDLERS
+// ============================================
+
+// GetSpendingReport returns budget spending per period with period-over-period
+// and year-over-year changes, rolling averages and top movers. Query:
+// granularity (monthly, quarterly or yearly; default monthly), from and to
+// (YYYY-MM-DD; default the year up to today), window (periods in the rolling
+// average; default 3) and top (movers returned; default 5).
+func (h *Handler) GetSpendingReport(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	p := services.ReportPeriod(c.Query("granularity", string(services.ReportMonthly)))
+	if !p.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "granularity must be monthly, quarterly or yearly"})
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be YYYY-MM-DD"})
+		}
+	}
+	from := to.AddDate(-1, 0, 1)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be YYYY-MM-DD"})
+		}
+	}
+	if from.After(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be on or before to"})
+	}
+	if services.ReportPeriods(p, from, to) > services.MaxReportPeriods {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "range covers too many periods"})
+	}
+
+	window := c.QueryInt("window", 3)
+	if window < 1 || window > 24 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "window must be between 1 and 24"})
+	}
+	top := c.QueryInt("top", 5)
+	if top < 0 || top > 50 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "top must be between 0 and 50"})
+	}
+
+	report, err := h.store.SpendingReport(profileID, p, from, to, window, top)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to build report"})
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/chart"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// SANKEY HANDLERS
+// ============================================
+
+// GetSankey returns the profile's flow graph laid out in columns.
+// Query: mode (planned or actual), month (YYYY-MM) or from/to (YYYY-MM-DD)
+// for the actual period, defaulting to this month, and min_share (0–1) to
+// collapse small nodes into "Other", and scenario to draw a what-if.
+func (h *Handler) GetSankey(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	opts, msg := parseSankeyOptions(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.BuildSankey(data, opts))
+}
+
+// RenderSankey draws the Sankey graph as an image for emails and reports.
+// Query: format (svg or png), width, height, background (#rrggbb), title,
+// plus everything GetSankey accepts.
+func (h *Handler) RenderSankey(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	opts, msg := parseSankeyOptions(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	format := c.Query("format", "svg")
+	if format != "svg" && format != "png" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be svg or png"})
+	}
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+	graph := services.BuildSankey(data, opts)
+
+	render := chart.Options{
+		Width:      c.QueryInt("width"),
+		Height:     c.QueryInt("height"),
+		Background: c.Query("background"),
+		Title:      c.Query("title"),
+	}
+
+	var image []byte
+	if format == "png" {
+		image, err = chart.SankeyPNG(graph, render)
+		c.Set(fiber.HeaderContentType, "image/png")
+	} else {
+		image, err = chart.SankeySVG(graph, render)
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+	}
+	if err != nil {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Send(image)
+}
+
+// parseSankeyOptions reads the Sankey query. A non-empty message means the
+// query was invalid.
+func parseSankeyOptions(c *fiber.Ctx) (services.SankeyOptions, string) {
+	opts := services.SankeyOptions{Mode: c.Query("mode", services.SankeyPlanned)}
+	if opts.Mode != services.SankeyPlanned && opts.Mode != services.SankeyActual {
+		return opts, "mode must be planned or actual"
+	}
+
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := c.Query("month"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil {
+			return opts, "month must be YYYY-MM"
+		}
+		month = t
+	}
+	opts.From, opts.To = month, month.AddDate(0, 1, -1)
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &opts.From}, {"to", &opts.To}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return opts, p.name + " must be YYYY-MM-DD"
+			}
+			*p.dst = t
+		}
+	}
+	if opts.From.After(opts.To) {
+		return opts, "from must be on or before to"
+	}
+
+	if v := c.Query("min_share"); v != "" {
+		share, err := strconv.ParseFloat(v, 64)
+		if err != nil || share < 0 || share >= 1 {
+			return opts, "min_share must be a number from 0 up to 1"
+		}
+		opts.MinShare = share
+	}
+
+	return opts, ""
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// SCENARIO HANDLERS
+// ============================================
+
+// loadProfileData loads the profile, with the scenario in ?scenario= applied
+// when one is given. It writes the error response itself; nil data means
+// the caller should return.
+func (h *Handler) loadProfileData(c *fiber.Ctx, profileID int64) (*services.ProfileData, error) {
+	var data *services.ProfileData
+	var err error
+
+	if v := c.Query("scenario"); v != "" {
+		scenarioID, perr := strconv.ParseInt(v, 10, 64)
+		if perr != nil {
+			return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid scenario ID"})
+		}
+		data, err = h.store.LoadScenario(profileID, scenarioID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "scenario not found"})
+		}
+	} else {
+		data, err = h.store.Load(profileID)
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load profile data"})
+	}
+
+	return data, nil
+}
+
+// scenario loads the scenario named in the route. It writes the error
+// response itself; a nil scenario means the caller should return.
+func (h *Handler) scenario(c *fiber.Ctx, profileID int64) (*models.Scenario, error) {
+	scenarioID, err := strconv.ParseInt(c.Params("scenarioId"), 10, 64)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid scenario ID"})
+	}
+
+	sc, err := h.store.Scenario(profileID, scenarioID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "scenario not found"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return &sc, nil
+}
+
+func (h *Handler) ListScenarios(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	scenarios, err := h.store.Scenarios(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.JSON(scenarios)
+}
+
+func (h *Handler) CreateScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateScenarioRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	name, description := req.Name, req.Description
+	if err := h.crypt.EncryptAll(&name, &description); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
+	result, err := h.db.Exec("INSERT INTO scenarios (profile_id, name, description) VALUES (?, ?, ?)", profileID, name, description)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create scenario"})
+	}
+
+	id, _ := result.LastInsertId()
+	return c.Status(fiber.StatusCreated).JSON(models.Scenario{
+		ID:          id,
+		ProfileID:   profileID,
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+		Changes:     []models.ScenarioChange{},
+	})
+}
+
+func (h *Handler) GetScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	sc, err := h.scenario(c, profileID)
+	if sc == nil {
+		return err
+	}
+
+	return c.JSON(sc)
+}
+
+func (h *Handler) UpdateScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	sc, err := h.scenario(c, profileID)
+	if sc == nil {
+		return err
+	}
+
+	var req models.CreateScenarioRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	name, description := req.Name, req.Description
+	if err := h.crypt.EncryptAll(&name, &description); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to encrypt data"})
+	}
+
+	if _, err := h.db.Exec("UPDATE scenarios SET name = ?, description = ? WHERE id = ?", name, description, sc.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update scenario"})
+	}
+
+	sc.Name, sc.Description = req.Name, req.Description
+	return c.JSON(sc)
+}
+
+func (h *Handler) DeleteScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	scenarioID, _ := strconv.ParseInt(c.Params("scenarioId"), 10, 64)
+
+	h.db.Exec("DELETE FROM scenario_changes WHERE scenario_id IN (SELECT id FROM scenarios WHERE id = ? AND profile_id = ?)", scenarioID, profileID)
+	_, err = h.db.Exec("DELETE FROM scenarios WHERE id = ? AND profile_id = ?", scenarioID, profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete scenario"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateScenarioChange adds an edit to a scenario. Body: kind plus the
+// fields it uses (see models.ScenarioChange).
+func (h *Handler) CreateScenarioChange(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	sc, err := h.scenario(c, profileID)
+	if sc == nil {
+		return err
+	}
+
+	var req models.ScenarioChange
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if err := h.store.ValidateScenarioChange(profileID, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.ScenarioID = sc.ID
+	change, err := h.store.AddScenarioChange(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create scenario change"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(change)
+}
+
+func (h *Handler) DeleteScenarioChange(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	sc, err := h.scenario(c, profileID)
+	if sc == nil {
+		return err
+	}
+
+	changeID, _ := strconv.ParseInt(c.Params("changeId"), 10, 64)
+	result, err := h.db.Exec("DELETE FROM scenario_changes WHERE id = ? AND scenario_id = ?", changeID, sc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete scenario change"})
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "scenario change not found"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CompareScenario returns the scenario's monthly cash flow and forecast
+// balances next to the baseline's. Query: months (default 12).
+func (h *Handler) CompareScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	months := c.QueryInt("months", 12)
+	if months < 1 || months > 120 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "months must be between 1 and 120"})
+	}
+
+	sc, err := h.scenario(c, profileID)
+	if sc == nil {
+		return err
+	}
+
+	baseline, err := h.store.Load(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load profile data"})
+	}
+
+	cmp := services.CompareScenario(baseline, services.ApplyScenario(baseline, *sc), time.Now(), months)
+	cmp.ScenarioID = sc.ID
+	return c.JSON(cmp)
+}
+
+// PromoteScenario makes a scenario the baseline: its changes are written to
+// the real nodes and flows and the scenario is deleted
+func (h *Handler) PromoteScenario(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	scenarioID, err := strconv.ParseInt(c.Params("scenarioId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid scenario ID"})
+	}
+
+	applied, skipped, err := h.store.PromoteScenario(profileID, scenarioID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "scenario not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to promote scenario"})
+	}
+
+	return c.JSON(fiber.Map{"id": scenarioID, "applied": applied, "skipped": skipped})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// SIMULATION HANDLERS
+// ============================================
+
+// GetSimulation runs a Monte Carlo projection of net worth and goals. Query:
+// paths (default 1000), months (default 120), seed (default 1), volatility
+// (annual percent for investment returns; default 15), income_volatility
+// and expense_volatility (monthly percent; default 5 and 10). The same
+// query against the same data always gives the same result.
+func (h *Handler) GetSimulation(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	opts := services.SimulationOptions{
+		Paths:             c.QueryInt("paths", services.DefaultSimulationPaths),
+		Months:            c.QueryInt("months", services.DefaultSimulationMonths),
+		Seed:              services.DefaultSimulationSeed,
+		Volatility:        c.QueryFloat("volatility", services.DefaultReturnVolatility),
+		IncomeVolatility:  c.QueryFloat("income_volatility", services.DefaultIncomeVolatility),
+		ExpenseVolatility: c.QueryFloat("expense_volatility", services.DefaultExpenseVolatility),
+	}
+	if v := c.Query("seed"); v != "" {
+		if opts.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "seed must be an integer"})
+		}
+	}
+	if opts.Paths < 1 || opts.Paths > services.MaxSimulationPaths {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "paths must be between 1 and 5000"})
+	}
+	if opts.Months < 1 || opts.Months > services.MaxSimulationMonths {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "months must be between 1 and 360"})
+	}
+	for _, v := range []float64{opts.Volatility, opts.IncomeVolatility, opts.ExpenseVolatility} {
+		if v < 0 || v > services.MaxSimulationVolatility {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "volatility must be between 0 and 100"})
+		}
+	}
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.Simulate(data, time.Now(), opts))
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+	"github.com/thejoshbq/vault-x/internal/services"
+)
+
+// ============================================
+// SINKING FUND HANDLERS
+// ============================================
+
+// ListSinkingFunds returns each fund with its funded, needed and monthly
+// set-aside amounts
+func (h *Handler) ListSinkingFunds(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	data, err := h.loadProfileData(c, profileID)
+	if data == nil {
+		return err
+	}
+
+	return c.JSON(services.AssessSinkingFunds(data, time.Now()))
+}
+
+// CreateSinkingFund links a quarterly or annual expense to the savings node
+// that sets it aside. Body: expense_id, node_id.
+func (h *Handler) CreateSinkingFund(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateSinkingFundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if err := h.store.ValidateSinkingFund(profileID, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	result, err := h.db.Exec("INSERT INTO sinking_funds (profile_id, expense_id, node_id) VALUES (?, ?, ?)", profileID, req.ExpenseID, req.NodeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create sinking fund"})
+	}
+	id, _ := result.LastInsertId()
+
+	data, err := h.store.Load(profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load profile data"})
+	}
+	for _, f := range services.AssessSinkingFunds(data, time.Now()) {
+		if f.ID == id {
+			return c.Status(fiber.StatusCreated).JSON(f)
+		}
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create sinking fund"})
+}
+
+func (h *Handler) DeleteSinkingFund(c *fiber.Ctx) error {
+	profileID, err := h.getProfileID(c)
+	if err != nil {
+		return err
+	}
+
+	fundID, _ := strconv.ParseInt(c.Params("fundId"), 10, 64)
+	result, err := h.db.Exec("DELETE FROM sinking_funds WHERE id = ? AND profile_id = ?", fundID, profileID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete sinking fund"})
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sinking fund not found"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
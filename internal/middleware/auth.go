@@ -86,3 +86,23 @@ func GenerateToken(userID int64, email string, secret string, expiry time.Durati
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
+
+// RequireAdmin restricts a route group to the configured admin emails.
+// Must be mounted after JWTAuth.
+func RequireAdmin(emails []string) fiber.Handler {
+	admins := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		admins[strings.ToLower(email)] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		email, _ := c.Locals("email").(string)
+		if !admins[strings.ToLower(email)] {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "admin access required",
+			})
+		}
+
+		return c.Next()
+	}
+}
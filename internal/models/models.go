@@ -27,17 +27,22 @@ type Profile struct {
 type Node struct {
 	ID          int64   `json:"id"`
 	ProfileID   int64   `json:"profile_id"`
-	Type        string  `json:"type"` // income, account, savings, investment, expense
+	Type        string  `json:"type"` // income, account, savings, investment, expense, budget, goal, liability, credit_card, loan
 	Label       string  `json:"label"`
 	Institution string  `json:"institution,omitempty"`
 	Amount      float64 `json:"amount,omitempty"`   // For income nodes
-	Balance     float64 `json:"balance,omitempty"`  // For account/savings/investment nodes
+	Balance     float64 `json:"balance,omitempty"`  // For account/savings/investment nodes; amount owed for liabilities
 	APY         float64 `json:"apy,omitempty"`      // For interest-bearing nodes
 	Budgeted    float64 `json:"budgeted,omitempty"` // For expense category nodes
 	Goal        float64 `json:"goal,omitempty"`     // Target balance for savings
 	Metadata    string  `json:"metadata,omitempty"` // JSON for extensibility
 	SortOrder   int     `json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
+	// Liability nodes (liability, credit_card, loan)
+	Principal      float64 `json:"principal,omitempty"`       // Original amount borrowed
+	InterestRate   float64 `json:"interest_rate,omitempty"`   // Annual rate, percent
+	TermMonths     int     `json:"term_months,omitempty"`
+	MinimumPayment float64 `json:"minimum_payment,omitempty"` // Monthly
 }
 
 // Flow represents money movement between nodes
@@ -49,6 +54,7 @@ type Flow struct {
 	Amount      float64 `json:"amount"`
 	Label       string  `json:"label,omitempty"`
 	IsRecurring bool    `json:"is_recurring"`
+	AnchorDate  string  `json:"anchor_date,omitempty"` // Recurs monthly from this day
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -67,16 +73,48 @@ type Budget struct {
 	Remaining   float64       `json:"remaining,omitempty"`
 	Percentage  float64       `json:"percentage,omitempty"`
 	Transactions []Transaction `json:"transactions,omitempty"`
+	// Pace over the budget's current week, month or year
+	PeriodStart        string  `json:"period_start,omitempty"`
+	PeriodEnd          string  `json:"period_end,omitempty"` // Last day, inclusive
+	DaysRemaining      int     `json:"days_remaining"`
+	ExpectedSpend      float64 `json:"expected_spend"`               // Budget spent evenly up to today
+	DailyBurn          float64 `json:"daily_burn"`
+	ProjectedSpend     float64 `json:"projected_spend"`              // Period total at the daily burn rate
+	DaysToExhaustion   *int    `json:"days_to_exhaustion,omitempty"` // Absent until something is spent
+	ProjectedOverspend bool    `json:"projected_overspend"`
+	Overspend          float64 `json:"overspend,omitempty"` // Projected amount over budget
+	PaceStatus         string  `json:"pace_status,omitempty"` // on_pace, over_pace, overspent
 }
 
 // Transaction represents a single expense against a budget
 type Transaction struct {
-	ID        int64     `json:"id"`
-	BudgetID  int64     `json:"budget_id"`
-	Amount    float64   `json:"amount"`
-	Note      string    `json:"note,omitempty"`
-	Date      string    `json:"date"` // YYYY-MM-DD
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64                `json:"id"`
+	BudgetID  int64                `json:"budget_id"`
+	Amount    float64              `json:"amount"`
+	Note      string               `json:"note,omitempty"`
+	Payee     string               `json:"payee,omitempty"`
+	Date      string               `json:"date"` // YYYY-MM-DD
+	CreatedAt time.Time            `json:"created_at"`
+	Anomalies []TransactionAnomaly `json:"anomalies,omitempty"` // Flagged when it was added
+}
+
+// TransactionAnomaly is a transaction the detector found unusual
+type TransactionAnomaly struct {
+	ID                   int64      `json:"id"`
+	TransactionID        int64      `json:"transaction_id"`
+	BudgetID             int64      `json:"budget_id"`
+	Kind                 string     `json:"kind"`                             // budget_amount, payee_amount, duplicate
+	Score                float64    `json:"score,omitempty"`                  // Modified z-score of the amount
+	Median               float64    `json:"median,omitempty"`                 // Typical amount it was scored against
+	RelatedTransactionID int64      `json:"related_transaction_id,omitempty"` // The charge a duplicate repeats
+	Status               string     `json:"status"`                           // open, dismissed, confirmed
+	CreatedAt            time.Time  `json:"created_at"`
+	ResolvedAt           *time.Time `json:"resolved_at,omitempty"`
+	// The transaction, for display
+	Amount float64 `json:"amount"`
+	Date   string  `json:"date"`
+	Payee  string  `json:"payee,omitempty"`
+	Note   string  `json:"note,omitempty"`
 }
 
 // GoalTransaction represents a contribution to a savings goal
@@ -87,6 +125,8 @@ type GoalTransaction struct {
 	Note      string    `json:"note,omitempty"`
 	Date      string    `json:"date"` // YYYY-MM-DD
 	CreatedAt time.Time `json:"created_at"`
+	FlowID    int64     `json:"flow_id,omitempty"` // Set when posted by a recurring flow
+	Kind      string    `json:"kind"`              // contribution, opening, adjustment, rollover or reconcile
 }
 
 // Goal represents a savings target
@@ -100,12 +140,44 @@ type Goal struct {
 	Deadline  string  `json:"deadline,omitempty"` // YYYY-MM-DD
 	Priority  int     `json:"priority"`
 	Color     string  `json:"color"`
+	Rollover  bool    `json:"rollover"` // Money over the target moves to the next goal
 	CreatedAt time.Time `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"` // When current last reached the target
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	Milestones  []GoalMilestone `json:"milestones,omitempty"`
 	// Computed fields
 	Percentage    float64            `json:"percentage,omitempty"`
 	DaysRemaining int                `json:"days_remaining,omitempty"`
-	MonthlyNeeded float64            `json:"monthly_needed,omitempty"`
+	MonthlyNeeded float64            `json:"monthly_needed,omitempty"` // Accounts for the linked node's APY
+	APY           float64            `json:"apy,omitempty"`
 	Transactions  []GoalTransaction  `json:"transactions,omitempty"`
+	// Feasibility, from contribution history and the flows into the goal's node
+	ContributionRate    float64 `json:"contribution_rate"`              // Trailing monthly average of contributions
+	PlannedRate         float64 `json:"planned_rate"`                   // Net monthly flows into the goal's node
+	ProjectedRate       float64 `json:"projected_rate"`                 // The rate the projection assumes
+	ProjectedCompletion string  `json:"projected_completion,omitempty"` // YYYY-MM-DD; empty if never reached
+	Status              string  `json:"status,omitempty"`               // complete, on_track, behind, overdue, stalled, archived
+	SuggestedMonthly    float64 `json:"suggested_monthly"`              // Share of the monthly surplus, by priority
+}
+
+// GoalMilestone is a threshold on the way to a goal: a percentage of the
+// target, or a fixed amount
+type GoalMilestone struct {
+	ID        int64      `json:"id"`
+	GoalID    int64      `json:"goal_id"`
+	Percent   float64    `json:"percent,omitempty"` // Zero for a fixed amount
+	Amount    float64    `json:"amount"`            // Threshold; for a percentage, at the current target
+	ReachedAt *time.Time `json:"reached_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// GoalEvent is one entry in a goal's history
+type GoalEvent struct {
+	Type        string    `json:"type"` // created, milestone, completed, archived
+	At          time.Time `json:"at"`
+	MilestoneID int64     `json:"milestone_id,omitempty"`
+	Percent     float64   `json:"percent,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
 }
 
 // Expense represents a fixed cost or subscription
@@ -125,6 +197,30 @@ type Expense struct {
 	AnnualAmount  float64 `json:"annual_amount,omitempty"`
 }
 
+// SinkingFund sets money aside in a savings node for a quarterly or annual
+// expense, so the bill is spread over the months before it falls due
+type SinkingFund struct {
+	ID        int64     `json:"id"`
+	ProfileID int64     `json:"profile_id"`
+	ExpenseID int64     `json:"expense_id"`
+	NodeID    int64     `json:"node_id"` // Savings node holding the money
+	LastPaid  string    `json:"last_paid,omitempty"` // YYYY-MM-DD due date of the last bill paid from the fund
+	CreatedAt time.Time `json:"created_at"`
+	// Computed
+	ExpenseName     string  `json:"expense_name"`
+	Period          string  `json:"period"`
+	NextDue         string  `json:"next_due"`
+	Needed          float64 `json:"needed"`            // The next bill
+	Funded          float64 `json:"funded"`            // The savings node's balance
+	Shortfall       float64 `json:"shortfall"`
+	Expected        float64 `json:"expected"`          // Balance a steady set-aside would have reached by now
+	MonthsRemaining int     `json:"months_remaining"`  // Set-asides left before the due date
+	MonthlySetAside float64 `json:"monthly_set_aside"` // Needed each month to cover the next bill
+	SteadyMonthly   float64 `json:"steady_monthly"`    // The bill spread over its period
+	Percentage      float64 `json:"percentage"`
+	Status          string  `json:"status"` // funded, on_track, behind
+}
+
 // === Request/Response DTOs ===
 
 type RegisterRequest struct {
@@ -165,6 +261,10 @@ type CreateNodeRequest struct {
 	Budgeted    float64 `json:"budgeted,omitempty"`
 	Goal        float64 `json:"goal,omitempty"`
 	Metadata    string  `json:"metadata,omitempty"`
+	Principal      float64 `json:"principal,omitempty"`
+	InterestRate   float64 `json:"interest_rate,omitempty"`
+	TermMonths     int     `json:"term_months,omitempty"`
+	MinimumPayment float64 `json:"minimum_payment,omitempty"`
 }
 
 type CreateFlowRequest struct {
@@ -173,6 +273,7 @@ type CreateFlowRequest struct {
 	Amount      float64 `json:"amount"`
 	Label       string  `json:"label,omitempty"`
 	IsRecurring bool    `json:"is_recurring"`
+	AnchorDate  string  `json:"anchor_date,omitempty"` // YYYY-MM-DD; defaults to when it's first seen
 }
 
 type CreateBudgetRequest struct {
@@ -186,6 +287,7 @@ type CreateBudgetRequest struct {
 type CreateTransactionRequest struct {
 	Amount float64 `json:"amount"`
 	Note   string  `json:"note,omitempty"`
+	Payee  string  `json:"payee,omitempty"`
 	Date   string  `json:"date,omitempty"` // Defaults to today
 }
 
@@ -202,6 +304,23 @@ type CreateGoalRequest struct {
 	Deadline string  `json:"deadline,omitempty"`
 	Priority int     `json:"priority"`
 	Color    string  `json:"color,omitempty"`
+	Rollover bool    `json:"rollover"`
+}
+
+type CreateSinkingFundRequest struct {
+	ExpenseID int64 `json:"expense_id"`
+	NodeID    int64 `json:"node_id"`
+}
+
+type CreateGoalMilestoneRequest struct {
+	Percent float64 `json:"percent,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+}
+
+type RestoreBackupRequest struct {
+	Name   string `json:"name,omitempty"`   // Exact snapshot name
+	At     string `json:"at,omitempty"`     // RFC3339; newest snapshot at or before this time
+	Source string `json:"source,omitempty"` // local (default) or remote
 }
 
 // Dashboard aggregated response
@@ -209,7 +328,9 @@ type DashboardResponse struct {
 	TotalIncome    float64   `json:"total_income"`
 	TotalExpenses  float64   `json:"total_expenses"`
 	NetSurplus     float64   `json:"net_surplus"`
+	DebtPayments   float64   `json:"debt_payments"`
 	TotalAssets    float64   `json:"total_assets"`
+	TotalLiabilities float64 `json:"total_liabilities"`
 	NetWorth       float64   `json:"net_worth"`
 	Nodes          []Node    `json:"nodes"`
 	Flows          []Flow    `json:"flows"`
@@ -218,12 +339,73 @@ type DashboardResponse struct {
 	RecentActivity []Transaction `json:"recent_activity"`
 }
 
+// SpendingReport is budget spending over a date range, bucketed by month,
+// quarter or year
+type SpendingReport struct {
+	From        string        `json:"from"` // First day of the first period
+	To          string        `json:"to"`   // Last day of the last period
+	Granularity string        `json:"granularity"`
+	Window      int           `json:"window"` // Periods in each rolling average
+	Periods     []string      `json:"periods"`
+	Totals      []PeriodSpend `json:"totals"` // All budgets together
+	Budgets     []BudgetTrend `json:"budgets"`
+	TopMovers   []BudgetMover `json:"top_movers"`
+}
+
+// PeriodSpend is spending in one period with its changes
+type PeriodSpend struct {
+	Period               string   `json:"period"` // 2026-03, 2026-Q1 or 2026
+	Spent                float64  `json:"spent"`
+	Change               float64  `json:"change"`                           // Against the previous period
+	ChangePercent        *float64 `json:"change_percent,omitempty"`         // Absent when the previous period is zero
+	YearOverYear         float64  `json:"year_over_year"`                   // Against the same period a year earlier
+	YearOverYearPercent  *float64 `json:"year_over_year_percent,omitempty"` // Absent when that period is zero
+	RollingAverage       float64  `json:"rolling_average"`                  // Over the window ending here
+}
+
+// BudgetTrend is one budget's spending across a report's periods
+type BudgetTrend struct {
+	BudgetID int64         `json:"budget_id"`
+	Name     string        `json:"name"`
+	Total    float64       `json:"total"`
+	Average  float64       `json:"average"` // Per period
+	Periods  []PeriodSpend `json:"periods"`
+}
+
+// BudgetMover is a budget whose spending changed most in the last period
+type BudgetMover struct {
+	BudgetID      int64    `json:"budget_id"`
+	Name          string   `json:"name"`
+	Period        string   `json:"period"`
+	Spent         float64  `json:"spent"`
+	Previous      float64  `json:"previous"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
 // Forecast response
 type ForecastResponse struct {
 	MonthlyAverage    float64           `json:"monthly_average"`
 	AnnualTotal       float64           `json:"annual_total"`
 	MonthlyProjection []MonthProjection `json:"monthly_projection"`
 	AnnualExpenses    []Expense         `json:"annual_expenses"`
+	BalanceProjections []BalanceProjection `json:"balance_projections"`
+}
+
+// BalanceProjection grows an APY-bearing node with its net monthly flows
+type BalanceProjection struct {
+	NodeID   int64          `json:"node_id"`
+	Label    string         `json:"label"`
+	APY      float64        `json:"apy"`
+	Current  float64        `json:"current"`
+	Interest float64        `json:"interest"` // Total over the projection
+	Months   []BalancePoint `json:"months"`
+}
+
+type BalancePoint struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Balance  float64 `json:"balance"`
+	Interest float64 `json:"interest"`
 }
 
 type MonthProjection struct {
@@ -232,3 +414,382 @@ type MonthProjection struct {
 	Spikes   float64 `json:"spikes"`
 	Total    float64 `json:"total"`
 }
+
+// CashFlowCalendar projects each account's balance day by day from its
+// scheduled flows and bills
+type CashFlowCalendar struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Floor    float64             `json:"floor"`
+	Accounts []AccountCashFlow   `json:"accounts"`
+	Warnings []LowBalanceWarning `json:"warnings"`
+}
+
+type AccountCashFlow struct {
+	NodeID     int64         `json:"node_id"`
+	Label      string        `json:"label"`
+	Type       string        `json:"type"`
+	Start      float64       `json:"start"` // Current balance
+	End        float64       `json:"end"`
+	Lowest     float64       `json:"lowest"`
+	LowestDate string        `json:"lowest_date"`
+	Days       []CashFlowDay `json:"days"`
+}
+
+type CashFlowDay struct {
+	Date       string          `json:"date"`    // YYYY-MM-DD
+	Balance    float64         `json:"balance"` // At the end of the day
+	In         float64         `json:"in,omitempty"`
+	Out        float64         `json:"out,omitempty"`
+	Payday     bool            `json:"payday,omitempty"`
+	Bill       bool            `json:"bill,omitempty"`
+	BelowFloor bool            `json:"below_floor,omitempty"`
+	Events     []CashFlowEvent `json:"events,omitempty"`
+}
+
+type CashFlowEvent struct {
+	Kind      string  `json:"kind"` // payday, bill, payment, transfer
+	Label     string  `json:"label,omitempty"`
+	Amount    float64 `json:"amount"` // Positive into the account
+	FlowID    int64   `json:"flow_id,omitempty"`
+	ExpenseID int64   `json:"expense_id,omitempty"`
+}
+
+// LowBalanceWarning is a run of days an account is projected below the floor
+type LowBalanceWarning struct {
+	NodeID     int64   `json:"node_id"`
+	Label      string  `json:"label"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Days       int     `json:"days"`
+	Lowest     float64 `json:"lowest"`
+	LowestDate string  `json:"lowest_date"`
+}
+
+// SimulationResponse is a Monte Carlo projection of net worth and goals
+type SimulationResponse struct {
+	Seed              int64             `json:"seed"`
+	Paths             int               `json:"paths"`
+	Months            int               `json:"months"`
+	Volatility        float64           `json:"volatility"`         // Annual, percent, of investment returns
+	IncomeVolatility  float64           `json:"income_volatility"`  // Monthly, percent
+	ExpenseVolatility float64           `json:"expense_volatility"` // Monthly, percent
+	NetWorth          float64           `json:"net_worth"`          // Today
+	Bands             []NetWorthBand    `json:"bands"`
+	Goals             []GoalProbability `json:"goals"`
+}
+
+// NetWorthBand is the spread of simulated net worth at the end of a month
+type NetWorthBand struct {
+	Month string  `json:"month"` // YYYY-MM
+	P5    float64 `json:"p5"`
+	P25   float64 `json:"p25"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P95   float64 `json:"p95"`
+	Mean  float64 `json:"mean"`
+}
+
+// GoalProbability is the share of simulated paths in which a goal is met
+type GoalProbability struct {
+	GoalID           int64    `json:"goal_id"`
+	Name             string   `json:"name"`
+	Target           float64  `json:"target"`
+	Current          float64  `json:"current"`
+	Deadline         string   `json:"deadline,omitempty"`
+	Probability      *float64 `json:"probability,omitempty"`       // By the deadline; absent without one
+	ProbabilityEver  float64  `json:"probability_ever"`            // Within the simulated months
+	MedianCompletion string   `json:"median_completion,omitempty"` // YYYY-MM; absent if under half the paths get there
+}
+
+// Amortization schedule for a liability node
+type AmortizationPayment struct {
+	Number    int     `json:"number"`
+	Date      string  `json:"date"` // YYYY-MM-DD
+	Payment   float64 `json:"payment"`
+	Principal float64 `json:"principal"` // Includes any extra payment
+	Interest  float64 `json:"interest"`
+	Extra     float64 `json:"extra,omitempty"`
+	Balance   float64 `json:"balance"` // Remaining after this payment
+}
+
+type AmortizationSchedule struct {
+	Months        int                   `json:"months"`
+	PayoffDate    string                `json:"payoff_date,omitempty"`
+	TotalInterest float64               `json:"total_interest"`
+	TotalPaid     float64               `json:"total_paid"`
+	Payments      []AmortizationPayment `json:"payments"`
+}
+
+type AmortizationResponse struct {
+	NodeID        int64                 `json:"node_id"`
+	Balance       float64               `json:"balance"`
+	InterestRate  float64               `json:"interest_rate"`
+	Payment       float64               `json:"payment"`
+	Schedule      AmortizationSchedule  `json:"schedule"`
+	WithExtra     *AmortizationSchedule `json:"with_extra,omitempty"`
+	InterestSaved float64               `json:"interest_saved,omitempty"`
+	MonthsSaved   int                   `json:"months_saved,omitempty"`
+}
+
+// Debt payoff plan across all liability nodes
+type DebtPlanPayment struct {
+	NodeID  int64   `json:"node_id"`
+	Payment float64 `json:"payment"`
+	Balance float64 `json:"balance"` // Remaining after this payment
+}
+
+type DebtPlanMonth struct {
+	Month     int               `json:"month"`
+	Date      string            `json:"date"` // YYYY-MM-DD
+	TotalPaid float64           `json:"total_paid"`
+	Remaining float64           `json:"remaining"`
+	Payments  []DebtPlanPayment `json:"payments"`
+}
+
+type DebtPayoff struct {
+	NodeID       int64   `json:"node_id"`
+	Label        string  `json:"label"`
+	Month        int     `json:"month"`
+	PayoffDate   string  `json:"payoff_date"`
+	InterestPaid float64 `json:"interest_paid"`
+}
+
+type DebtPlan struct {
+	Strategy      string          `json:"strategy"`
+	Months        int             `json:"months"`
+	DebtFreeDate  string          `json:"debt_free_date,omitempty"`
+	TotalInterest float64         `json:"total_interest"`
+	TotalPaid     float64         `json:"total_paid"`
+	Payoffs       []DebtPayoff    `json:"payoffs"` // In payoff order
+	Schedule      []DebtPlanMonth `json:"schedule"`
+}
+
+type DebtPlanResponse struct {
+	MonthlyBudget   float64    `json:"monthly_budget"`
+	MinimumPayments float64    `json:"minimum_payments"`
+	TotalDebt       float64    `json:"total_debt"`
+	Plans           []DebtPlan `json:"plans"`
+	Recommended     string     `json:"recommended,omitempty"` // Least total interest
+}
+
+// InterestAccrual records interest credited to a node for one compounding period
+type InterestAccrual struct {
+	ID           int64     `json:"id"`
+	NodeID       int64     `json:"node_id"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	APY          float64   `json:"apy"`
+	PeriodStart  string    `json:"period_start"` // YYYY-MM-DD
+	PeriodEnd    string    `json:"period_end"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Balance history
+type HistoryPoint struct {
+	Date    string  `json:"date"` // End of the period, YYYY-MM-DD
+	Balance float64 `json:"balance"`
+}
+
+type NetWorthPoint struct {
+	Date        string  `json:"date"`
+	Assets      float64 `json:"assets"`
+	Liabilities float64 `json:"liabilities"`
+	NetWorth    float64 `json:"net_worth"`
+}
+
+// Holding is a security held in an investment node, valued from its
+// transactions and the latest price
+type Holding struct {
+	ID             int64     `json:"id"`
+	NodeID         int64     `json:"node_id"`
+	Symbol         string    `json:"symbol"`
+	AssetClass     string    `json:"asset_class"`
+	CreatedAt      time.Time `json:"created_at"`
+	Quantity       float64   `json:"quantity"`
+	CostBasis      float64   `json:"cost_basis"`
+	Price          float64   `json:"price"`
+	PriceDate      string    `json:"price_date,omitempty"` // Empty if never priced or traded
+	MarketValue    float64   `json:"market_value"`
+	UnrealizedGain float64   `json:"unrealized_gain"`
+	RealizedGain   float64   `json:"realized_gain"`
+	Dividends      float64   `json:"dividends"`
+	Lots           []Lot     `json:"lots"`
+}
+
+// Lot is the unsold part of one purchase
+type Lot struct {
+	Date      string  `json:"date"`
+	Quantity  float64 `json:"quantity"`
+	Price     float64 `json:"price"` // Cost per unit
+	CostBasis float64 `json:"cost_basis"`
+}
+
+type HoldingTransaction struct {
+	ID        int64     `json:"id"`
+	HoldingID int64     `json:"holding_id"`
+	Type      string    `json:"type"` // buy, sell, dividend
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Amount    float64   `json:"amount"` // Cash moved: quantity × price, or the dividend paid
+	Date      string    `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateHoldingRequest struct {
+	Symbol     string `json:"symbol"`
+	AssetClass string `json:"asset_class"`
+}
+
+type CreateHoldingTransactionRequest struct {
+	Type     string  `json:"type"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Amount   float64 `json:"amount"` // Dividends only
+	Date     string  `json:"date"`
+}
+
+type Price struct {
+	Symbol string  `json:"symbol"`
+	Date   string  `json:"date"`
+	Price  float64 `json:"price"`
+}
+
+type AssetAllocation struct {
+	AssetClass  string  `json:"asset_class"`
+	MarketValue float64 `json:"market_value"`
+	Percent     float64 `json:"percent"`
+}
+
+type InvestmentSummary struct {
+	MarketValue    float64           `json:"market_value"`
+	CostBasis      float64           `json:"cost_basis"`
+	UnrealizedGain float64           `json:"unrealized_gain"`
+	RealizedGain   float64           `json:"realized_gain"`
+	Dividends      float64           `json:"dividends"`
+	Allocation     []AssetAllocation `json:"allocation"`
+	Holdings       []Holding         `json:"holdings"`
+}
+
+// FlowIssue is one problem found in a profile's flow graph
+type FlowIssue struct {
+	Severity string `json:"severity"` // error or warning
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	NodeID   int64  `json:"node_id,omitempty"`
+	FlowID   int64  `json:"flow_id,omitempty"`
+}
+
+// NodeFlowBalance is the money moving through one node
+type NodeFlowBalance struct {
+	NodeID  int64   `json:"node_id"`
+	Label   string  `json:"label"`
+	Type    string  `json:"type"`
+	Inflow  float64 `json:"inflow"` // Income nodes count their own amount
+	Outflow float64 `json:"outflow"`
+	Net     float64 `json:"net"` // Inflow − outflow
+}
+
+type FlowValidationReport struct {
+	Valid             bool              `json:"valid"` // No error-severity issues
+	UnallocatedIncome float64           `json:"unallocated_income"`
+	Issues            []FlowIssue       `json:"issues"`
+	Cycles            [][]int64         `json:"cycles"` // Node IDs, first node repeated at the end
+	Nodes             []NodeFlowBalance `json:"nodes"`
+}
+
+// Sankey graph, laid out in columns by node type
+type SankeyNode struct {
+	ID        int64   `json:"id"` // Negative for a column's "Other" node
+	Label     string  `json:"label"`
+	Type      string  `json:"type"`
+	Column    int     `json:"column"`
+	Color     string  `json:"color"`
+	Value     float64 `json:"value"`               // Larger of money in and money out
+	Collapsed []int64 `json:"collapsed,omitempty"` // Nodes merged into "Other"
+}
+
+type SankeyLink struct {
+	Source  int64   `json:"source"`
+	Target  int64   `json:"target"`
+	Value   float64 `json:"value"`   // Actual in actual mode where transactions exist
+	Planned float64 `json:"planned"` // Sum of the flows' amounts
+	Color   string  `json:"color"`
+}
+
+type SankeyColumn struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+type SankeyResponse struct {
+	Mode        string         `json:"mode"` // planned or actual
+	From        string         `json:"from,omitempty"`
+	To          string         `json:"to,omitempty"`
+	TotalIncome float64        `json:"total_income"`
+	Columns     []SankeyColumn `json:"columns"`
+	Nodes       []SankeyNode   `json:"nodes"`
+	Links       []SankeyLink   `json:"links"`
+}
+
+// Scenario is a named what-if overlay on a profile's nodes and flows. Its
+// changes are applied to a copy of the data; nothing real is touched until
+// it is promoted.
+type Scenario struct {
+	ID          int64            `json:"id"`
+	ProfileID   int64            `json:"profile_id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Changes     []ScenarioChange `json:"changes"`
+}
+
+// ScenarioChange is one edit in a scenario. Which fields are used depends
+// on the kind: node_amount sets NodeID's amount, flow_amount sets FlowID's
+// amount, add_flow adds a flow from FromNodeID to ToNodeID and remove_flow
+// drops FlowID.
+type ScenarioChange struct {
+	ID         int64     `json:"id"`
+	ScenarioID int64     `json:"scenario_id"`
+	Kind       string    `json:"kind"`
+	NodeID     int64     `json:"node_id,omitempty"`
+	FlowID     int64     `json:"flow_id,omitempty"`
+	FromNodeID int64     `json:"from_node_id,omitempty"`
+	ToNodeID   int64     `json:"to_node_id,omitempty"`
+	Amount     float64   `json:"amount"`
+	Label      string    `json:"label,omitempty"`
+	Stale      bool      `json:"stale,omitempty"` // Its node or flow has since been deleted
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateScenarioRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ScenarioTotals are the monthly figures compared between a scenario and
+// the baseline
+type ScenarioTotals struct {
+	TotalIncome       float64 `json:"total_income"`
+	TotalExpenses     float64 `json:"total_expenses"`
+	DebtPayments      float64 `json:"debt_payments"`
+	NetSurplus        float64 `json:"net_surplus"`
+	ProjectedBalances float64 `json:"projected_balances"` // Interest-bearing balances at the end of the forecast
+}
+
+type ScenarioBalanceDelta struct {
+	NodeID     int64   `json:"node_id"`
+	Label      string  `json:"label"`
+	Baseline   float64 `json:"baseline"`
+	Scenario   float64 `json:"scenario"`
+	Difference float64 `json:"difference"`
+}
+
+type ScenarioComparison struct {
+	ScenarioID int64                  `json:"scenario_id"`
+	Months     int                    `json:"months"`
+	Baseline   ScenarioTotals         `json:"baseline"`
+	Scenario   ScenarioTotals         `json:"scenario"`
+	Difference ScenarioTotals         `json:"difference"`
+	Balances   []ScenarioBalanceDelta `json:"balances"`
+}
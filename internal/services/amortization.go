@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// maxAmortizationMonths bounds a schedule (100 years)
+const maxAmortizationMonths = 1200
+
+var (
+	ErrNoPayment       = errors.New("loan has no payment: set minimum_payment, or principal and term_months")
+	ErrNeverPaysOff    = errors.New("payment does not cover the monthly interest")
+	ErrScheduleTooLong = errors.New("loan takes more than 100 years to pay off")
+)
+
+// LumpSum is a one-off extra payment applied with the first scheduled
+// payment on or after Date
+type LumpSum struct {
+	Date   time.Time
+	Amount float64
+}
+
+// ExtraPayments are applied to principal on top of the scheduled payment
+type ExtraPayments struct {
+	Monthly  float64
+	LumpSums []LumpSum
+}
+
+func (e ExtraPayments) Empty() bool {
+	return e.Monthly == 0 && len(e.LumpSums) == 0
+}
+
+// LoanPayment is the fixed monthly payment that repays principal over
+// months at an annual percentage rate
+func LoanPayment(principal, annualRate float64, months int) float64 {
+	if months <= 0 {
+		return 0
+	}
+	r := annualRate / 100 / 12
+	if r == 0 {
+		return RoundCents(principal / float64(months))
+	}
+	return RoundCents(principal * r / (1 - math.Pow(1+r, -float64(months))))
+}
+
+// ScheduledPayment works out a liability's regular monthly payment: the
+// annuity payment on the original principal and term if both are known,
+// never less than the minimum payment
+func ScheduledPayment(n models.Node) float64 {
+	payment := n.MinimumPayment
+	switch {
+	case n.Principal > 0 && n.TermMonths > 0:
+		payment = math.Max(payment, LoanPayment(n.Principal, n.InterestRate, n.TermMonths))
+	case n.TermMonths > 0:
+		payment = math.Max(payment, LoanPayment(n.Balance, n.InterestRate, n.TermMonths))
+	}
+	return payment
+}
+
+// Amortize builds the payment schedule for a balance at an annual
+// percentage rate, starting with a payment on first and monthly after that
+func Amortize(balance, annualRate, payment float64, first time.Time, extra ExtraPayments) (models.AmortizationSchedule, error) {
+	schedule := models.AmortizationSchedule{Payments: []models.AmortizationPayment{}}
+	if balance <= 0 {
+		return schedule, nil
+	}
+	if payment <= 0 {
+		return schedule, ErrNoPayment
+	}
+
+	r := annualRate / 100 / 12
+	if RoundCents(balance*r) >= payment+extra.Monthly {
+		return schedule, ErrNeverPaysOff
+	}
+
+	lumps := append([]LumpSum{}, extra.LumpSums...)
+	sort.Slice(lumps, func(i, j int) bool { return lumps[i].Date.Before(lumps[j].Date) })
+
+	for n := 1; balance > 0.005; n++ {
+		if n > maxAmortizationMonths {
+			return schedule, ErrScheduleTooLong
+		}
+
+		date := AddMonths(first, n-1)
+		interest := RoundCents(balance * r)
+		scheduled := math.Min(payment, balance+interest)
+		principal := scheduled - interest
+
+		additional := extra.Monthly
+		for len(lumps) > 0 && !lumps[0].Date.After(date) {
+			additional += lumps[0].Amount
+			lumps = lumps[1:]
+		}
+		additional = RoundCents(math.Max(0, math.Min(additional, balance-principal)))
+
+		balance = RoundCents(balance - principal - additional)
+		schedule.Payments = append(schedule.Payments, models.AmortizationPayment{
+			Number:    n,
+			Date:      date.Format("2006-01-02"),
+			Payment:   RoundCents(scheduled + additional),
+			Principal: RoundCents(principal + additional),
+			Interest:  interest,
+			Extra:     additional,
+			Balance:   math.Max(balance, 0),
+		})
+		schedule.TotalInterest += interest
+		schedule.TotalPaid += scheduled + additional
+	}
+
+	schedule.Months = len(schedule.Payments)
+	schedule.TotalInterest = RoundCents(schedule.TotalInterest)
+	schedule.TotalPaid = RoundCents(schedule.TotalPaid)
+	schedule.PayoffDate = schedule.Payments[schedule.Months-1].Date
+
+	return schedule, nil
+}
+
+// AddMonths adds calendar months, clamping to the last day of shorter
+// months (Jan 31 + 1 month is Feb 28, not Mar 3)
+func AddMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// RoundCents rounds to whole cents
+func RoundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Anomaly kinds
+const (
+	AnomalyBudgetAmount = "budget_amount" // Far above what the budget usually sees
+	AnomalyPayeeAmount  = "payee_amount"  // Far above what the payee usually charges
+	AnomalyDuplicate    = "duplicate"     // Same amount in the same budget a few days apart
+)
+
+// Anomaly statuses. Confirmed anomalies are left out of the history later
+// transactions are scored against; dismissed ones count as normal spending.
+const (
+	AnomalyOpen      = "open"
+	AnomalyDismissed = "dismissed"
+	AnomalyConfirmed = "confirmed"
+)
+
+const (
+	// anomalyMinHistory is the fewest past transactions worth scoring against
+	anomalyMinHistory = 5
+	// anomalyThreshold is the modified z-score above which an amount is flagged
+	anomalyThreshold = 3.5
+	// anomalyHistoryMonths is how far back the history reaches
+	anomalyHistoryMonths = 12
+	// duplicateWindowDays is how many days apart a duplicate charge can land
+	duplicateWindowDays = 3
+	// anomalyMinSpread keeps a history of near-identical amounts from
+	// flagging every small change: the spread is at least this share of
+	// the median
+	anomalyMinSpread = 0.1
+)
+
+// madScale turns the median absolute deviation into an estimate of the
+// standard deviation for normally distributed amounts
+const madScale = 0.6745
+
+// DetectAnomalies scores a transaction against the history of its budget
+// and of its payee, using the median and median absolute deviation of the
+// past year's amounts, and checks it for a duplicate of the same amount
+// within a few days. Only unusually high amounts are flagged. New anomalies
+// are stored as open and returned; a transaction is flagged at most once
+// per kind.
+func (s *Store) DetectAnomalies(profileID, txID int64) ([]models.TransactionAnomaly, error) {
+	var t models.Transaction
+	var note, payee sql.NullString
+	err := s.db.QueryRow(`
+		SELECT t.id, t.budget_id, t.amount, t.note, t.payee, t.date, t.created_at
+		FROM transactions t
+		JOIN budgets b ON b.id = t.budget_id
+		WHERE t.id = ? AND b.profile_id = ?
+	`, txID, profileID).Scan(&t.ID, &t.BudgetID, &t.Amount, &note, &payee, &t.Date, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Note, t.Payee = note.String, payee.String
+	t.Date = DateOnly(t.Date)
+	if err := s.crypt.DecryptAll(&t.Note, &t.Payee); err != nil {
+		return nil, err
+	}
+	date, err := time.Parse("2006-01-02", t.Date)
+	if err != nil {
+		return nil, nil
+	}
+
+	history, err := s.anomalyHistory(profileID, t.ID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var byBudget, byPayee []float64
+	var duplicate *models.Transaction
+	for i, h := range history {
+		when, err := time.Parse("2006-01-02", h.Date)
+		if err != nil {
+			continue
+		}
+		if !when.After(date) {
+			if h.BudgetID == t.BudgetID {
+				byBudget = append(byBudget, h.Amount)
+			}
+			if t.Payee != "" && samePayee(h.Payee, t.Payee) {
+				byPayee = append(byPayee, h.Amount)
+			}
+		}
+
+		days := math.Abs(when.Sub(date).Hours() / 24)
+		if h.BudgetID == t.BudgetID && RoundCents(h.Amount) == RoundCents(t.Amount) && days <= duplicateWindowDays &&
+			(h.Payee == "" || t.Payee == "" || samePayee(h.Payee, t.Payee)) {
+			// The closest earlier-added charge is the one this repeats
+			if h.ID < t.ID && (duplicate == nil || h.ID > duplicate.ID) {
+				duplicate = &history[i]
+			}
+		}
+	}
+
+	found := []models.TransactionAnomaly{}
+	if score, median, ok := amountScore(t.Amount, byBudget); ok {
+		found = append(found, models.TransactionAnomaly{Kind: AnomalyBudgetAmount, Score: score, Median: median})
+	}
+	if score, median, ok := amountScore(t.Amount, byPayee); ok {
+		found = append(found, models.TransactionAnomaly{Kind: AnomalyPayeeAmount, Score: score, Median: median})
+	}
+	if duplicate != nil {
+		found = append(found, models.TransactionAnomaly{Kind: AnomalyDuplicate, RelatedTransactionID: duplicate.ID})
+	}
+
+	flagged := []models.TransactionAnomaly{}
+	for _, a := range found {
+		var related any
+		if a.RelatedTransactionID != 0 {
+			related = a.RelatedTransactionID
+		}
+		result, err := s.db.Exec(`
+			INSERT OR IGNORE INTO transaction_anomalies
+				(profile_id, transaction_id, budget_id, kind, score, median, related_transaction_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, profileID, t.ID, t.BudgetID, a.Kind, a.Score, a.Median, related)
+		if err != nil {
+			return flagged, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		a.ID, _ = result.LastInsertId()
+		a.TransactionID = t.ID
+		a.BudgetID = t.BudgetID
+		a.Status = AnomalyOpen
+		a.CreatedAt = time.Now()
+		a.Amount, a.Date, a.Payee, a.Note = t.Amount, t.Date, t.Payee, t.Note
+		flagged = append(flagged, a)
+	}
+
+	return flagged, nil
+}
+
+// anomalyHistory loads the profile's other transactions from the history
+// window before date through the duplicate window after it, leaving out
+// those with a confirmed anomaly. Payees are encrypted at rest, so they're
+// matched here rather than in SQL.
+func (s *Store) anomalyHistory(profileID, txID int64, date time.Time) ([]models.Transaction, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.budget_id, t.amount, t.payee, t.date
+		FROM transactions t
+		JOIN budgets b ON b.id = t.budget_id
+		WHERE b.profile_id = ? AND t.id != ? AND t.date >= ? AND t.date <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM transaction_anomalies a
+			WHERE a.transaction_id = t.id AND a.status = 'confirmed'
+		)
+	`, profileID, txID,
+		AddMonths(date, -anomalyHistoryMonths).Format("2006-01-02"),
+		date.AddDate(0, 0, duplicateWindowDays).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		var payee sql.NullString
+		if err := rows.Scan(&t.ID, &t.BudgetID, &t.Amount, &payee, &t.Date); err != nil {
+			return nil, err
+		}
+		t.Payee = payee.String
+		t.Date = DateOnly(t.Date)
+		if err := s.crypt.DecryptAll(&t.Payee); err != nil {
+			return nil, err
+		}
+		history = append(history, t)
+	}
+
+	return history, rows.Err()
+}
+
+func samePayee(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// amountScore is the modified z-score of amount against history, and
+// whether it's high enough to flag. Too short a history is never flagged.
+func amountScore(amount float64, history []float64) (score, med float64, flagged bool) {
+	if len(history) < anomalyMinHistory {
+		return 0, 0, false
+	}
+
+	med = median(history)
+	deviations := make([]float64, len(history))
+	for i, v := range history {
+		deviations[i] = math.Abs(v - med)
+	}
+	spread := math.Max(median(deviations)/madScale, anomalyMinSpread*math.Abs(med))
+	if spread == 0 {
+		return 0, med, false
+	}
+
+	score = math.Round((amount-med)/spread*100) / 100
+	return score, RoundCents(med), score > anomalyThreshold
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Anomalies lists the profile's anomalies with the transactions they flag,
+// newest first. An empty status lists them all.
+func (s *Store) Anomalies(profileID int64, status string) ([]models.TransactionAnomaly, error) {
+	rows, err := s.db.Query(`
+		SELECT a.id, a.transaction_id, a.budget_id, a.kind, a.score, a.median,
+			a.related_transaction_id, a.status, a.created_at, a.resolved_at,
+			t.amount, t.date, t.payee, t.note
+		FROM transaction_anomalies a
+		JOIN transactions t ON t.id = a.transaction_id
+		WHERE a.profile_id = ? AND (? = '' OR a.status = ?)
+		ORDER BY t.date DESC, a.id DESC
+	`, profileID, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anomalies := []models.TransactionAnomaly{}
+	for rows.Next() {
+		var a models.TransactionAnomaly
+		var related sql.NullInt64
+		var payee, note sql.NullString
+		if err := rows.Scan(&a.ID, &a.TransactionID, &a.BudgetID, &a.Kind, &a.Score, &a.Median,
+			&related, &a.Status, &a.CreatedAt, &a.ResolvedAt,
+			&a.Amount, &a.Date, &payee, &note); err != nil {
+			return nil, err
+		}
+		a.RelatedTransactionID = related.Int64
+		a.Date = DateOnly(a.Date)
+		a.Payee, a.Note = payee.String, note.String
+		if err := s.crypt.DecryptAll(&a.Payee, &a.Note); err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, a)
+	}
+
+	return anomalies, rows.Err()
+}
+
+// ResolveAnomaly marks an anomaly dismissed or confirmed. Returns
+// sql.ErrNoRows if the profile doesn't own it.
+func (s *Store) ResolveAnomaly(profileID, anomalyID int64, status string) error {
+	result, err := s.db.Exec(`
+		UPDATE transaction_anomalies SET status = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND profile_id = ?
+	`, status, anomalyID, profileID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,618 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// ArchiveVersion is the schema version written by ExportUser.
+// Bump it whenever the archive layout changes in a way older readers can't handle.
+const ArchiveVersion = 1
+
+// UserArchive is a full-fidelity, portable copy of everything a user owns
+type UserArchive struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Email      string           `json:"email"`
+	Profiles   []ProfileArchive `json:"profiles"`
+}
+
+type ProfileArchive struct {
+	Profile          models.Profile           `json:"profile"`
+	Nodes            []models.Node            `json:"nodes"`
+	Flows            []models.Flow            `json:"flows"`
+	Budgets          []models.Budget          `json:"budgets"`
+	Transactions     []models.Transaction     `json:"transactions"`
+	Goals            []models.Goal            `json:"goals"`
+	GoalTransactions []models.GoalTransaction `json:"goal_transactions"`
+	Expenses         []models.Expense         `json:"expenses"`
+
+	Holdings            []models.Holding            `json:"holdings,omitempty"`
+	HoldingTransactions []models.HoldingTransaction `json:"holding_transactions,omitempty"`
+	Prices              []models.Price              `json:"prices,omitempty"`
+
+	SinkingFunds []models.SinkingFund `json:"sinking_funds,omitempty"`
+	Scenarios    []models.Scenario    `json:"scenarios,omitempty"`
+}
+
+// Conflict modes for profiles whose name already exists for the user
+const (
+	ImportSkip   = "skip"
+	ImportRename = "rename"
+)
+
+type ImportOptions struct {
+	DryRun     bool
+	OnConflict string // skip (default) or rename
+}
+
+type ImportConflict struct {
+	Profile string `json:"profile"`
+	Entity  string `json:"entity"`
+	ID      int64  `json:"id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type ImportedProfile struct {
+	SourceID int64  `json:"source_id"`
+	ID       int64  `json:"id,omitempty"` // Zero on dry runs
+	Name     string `json:"name"`
+	Skipped  bool   `json:"skipped,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Version   int               `json:"version"`
+	Profiles  []ImportedProfile `json:"profiles"`
+	Created   map[string]int    `json:"created"`
+	Conflicts []ImportConflict  `json:"conflicts"`
+}
+
+// ExportUser serializes every profile owned by the user
+func (s *Store) ExportUser(userID int64) (*UserArchive, error) {
+	archive := &UserArchive{
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Profiles:   []ProfileArchive{},
+	}
+
+	if err := s.db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&archive.Email); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, user_id, name, avatar_color, is_owner, created_at FROM profiles WHERE user_id = ? ORDER BY id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	profiles := []models.Profile{}
+	for rows.Next() {
+		var p models.Profile
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.AvatarColor, &p.IsOwner, &p.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	rows.Close()
+
+	for _, p := range profiles {
+		data, err := s.Load(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		holdings, err := loadHoldings(s.db, p.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		holdingTxs, err := loadHoldingTransactions(s.db, p.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		prices, err := s.allPrices(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		scenarios, err := s.Scenarios(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		archive.Profiles = append(archive.Profiles, ProfileArchive{
+			Profile:          p,
+			Nodes:            data.Nodes,
+			Flows:            data.Flows,
+			Budgets:          data.Budgets,
+			Transactions:     data.Transactions,
+			Goals:            data.Goals,
+			GoalTransactions: data.GoalTransactions,
+			Expenses:         data.Expenses,
+
+			Holdings:            holdings,
+			HoldingTransactions: holdingTxs,
+			Prices:              prices,
+
+			SinkingFunds: data.SinkingFunds,
+			Scenarios:    scenarios,
+		})
+	}
+
+	return archive, nil
+}
+
+// ImportUser restores an archive into the user's account. Every row gets a
+// fresh ID and references are remapped; rows that can't be placed are
+// reported as conflicts instead of aborting the import. Dry runs perform the
+// same work inside a transaction that is rolled back.
+func (s *Store) ImportUser(userID int64, archive *UserArchive, opts ImportOptions) (*ImportReport, error) {
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d (this server reads up to %d)", archive.Version, ArchiveVersion)
+	}
+	if opts.OnConflict == "" {
+		opts.OnConflict = ImportSkip
+	}
+	if opts.OnConflict != ImportSkip && opts.OnConflict != ImportRename {
+		return nil, fmt.Errorf("on_conflict must be %s or %s", ImportSkip, ImportRename)
+	}
+
+	report := &ImportReport{
+		DryRun:    opts.DryRun,
+		Version:   archive.Version,
+		Profiles:  []ImportedProfile{},
+		Created:   map[string]int{},
+		Conflicts: []ImportConflict{},
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing := map[string]bool{}
+	rows, err := tx.Query("SELECT name FROM profiles WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, pa := range archive.Profiles {
+		name := pa.Profile.Name
+		if existing[name] {
+			if opts.OnConflict == ImportSkip {
+				report.Conflicts = append(report.Conflicts, ImportConflict{
+					Profile: name, Entity: "profile", ID: pa.Profile.ID,
+					Reason: "a profile with this name already exists",
+				})
+				report.Profiles = append(report.Profiles, ImportedProfile{SourceID: pa.Profile.ID, Name: name, Skipped: true})
+				continue
+			}
+			name = uniqueName(name, existing)
+		}
+		existing[name] = true
+
+		profileID, err := s.importProfile(tx, userID, name, pa, report)
+		if err != nil {
+			return nil, err
+		}
+		imported := ImportedProfile{SourceID: pa.Profile.ID, Name: name}
+		if !opts.DryRun {
+			imported.ID = profileID
+		}
+		report.Profiles = append(report.Profiles, imported)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Store) importProfile(tx *sql.Tx, userID int64, name string, pa ProfileArchive, report *ImportReport) (int64, error) {
+	conflict := func(entity string, id int64, reason string) {
+		report.Conflicts = append(report.Conflicts, ImportConflict{Profile: name, Entity: entity, ID: id, Reason: reason})
+	}
+
+	color := pa.Profile.AvatarColor
+	if color == "" {
+		color = "#10b981"
+	}
+	// Imported profiles never become the owner profile
+	result, err := tx.Exec(
+		"INSERT INTO profiles (user_id, name, avatar_color, is_owner, created_at) VALUES (?, ?, ?, 0, ?)",
+		userID, name, color, createdAt(pa.Profile.CreatedAt),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create profile %q: %w", name, err)
+	}
+	profileID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	report.Created["profiles"]++
+
+	nodeIDs := map[int64]int64{}
+	for _, n := range pa.Nodes {
+		if err := s.crypt.EncryptAll(&n.Label, &n.Institution); err != nil {
+			conflict("node", n.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO nodes (profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata, sort_order, created_at,
+				principal, interest_rate, term_months, minimum_payment)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, profileID, n.Type, n.Label, n.Institution, n.Amount, n.Balance, n.APY, n.Budgeted, n.Goal, n.Metadata, n.SortOrder, createdAt(n.CreatedAt),
+			n.Principal, n.InterestRate, n.TermMonths, n.MinimumPayment)
+		if err != nil {
+			conflict("node", n.ID, err.Error())
+			continue
+		}
+		nodeIDs[n.ID], _ = result.LastInsertId()
+		report.Created["nodes"]++
+		if HasBalance(n.Type) {
+			if err := RecordBalance(tx, nodeIDs[n.ID], n.Balance, BalanceImported); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	flowIDs := map[int64]int64{}
+	for _, f := range pa.Flows {
+		from, okFrom := nodeIDs[f.FromNodeID]
+		to, okTo := nodeIDs[f.ToNodeID]
+		if !okFrom || !okTo {
+			conflict("flow", f.ID, "references a node that was not imported")
+			continue
+		}
+		if from == to {
+			conflict("flow", f.ID, ErrSelfLoop.Error())
+			continue
+		}
+		if err := s.crypt.EncryptAll(&f.Label); err != nil {
+			conflict("flow", f.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO flows (profile_id, from_node_id, to_node_id, amount, label, is_recurring, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, profileID, from, to, f.Amount, f.Label, f.IsRecurring, createdAt(f.CreatedAt))
+		if err != nil {
+			conflict("flow", f.ID, err.Error())
+			continue
+		}
+		id, _ := result.LastInsertId()
+		flowIDs[f.ID] = id
+		if _, perr := time.Parse("2006-01-02", f.AnchorDate); perr == nil {
+			if err := AnchorFlow(tx, id, f.AnchorDate, time.Now()); err != nil {
+				return 0, err
+			}
+		}
+		report.Created["flows"]++
+	}
+
+	budgetIDs := map[int64]int64{}
+	for _, b := range pa.Budgets {
+		nodeID, ok := remapOptional(b.NodeID, nodeIDs)
+		if !ok {
+			conflict("budget", b.ID, "linked node was not imported; budget left unlinked")
+		}
+		if err := s.crypt.EncryptAll(&b.Name); err != nil {
+			conflict("budget", b.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO budgets (profile_id, node_id, name, budgeted, period, color, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, profileID, nodeID, b.Name, b.Budgeted, b.Period, b.Color, createdAt(b.CreatedAt))
+		if err != nil {
+			conflict("budget", b.ID, err.Error())
+			continue
+		}
+		budgetIDs[b.ID], _ = result.LastInsertId()
+		report.Created["budgets"]++
+	}
+
+	for _, t := range pa.Transactions {
+		budgetID, ok := budgetIDs[t.BudgetID]
+		if !ok {
+			conflict("transaction", t.ID, "references a budget that was not imported")
+			continue
+		}
+		if err := s.crypt.EncryptAll(&t.Note, &t.Payee); err != nil {
+			conflict("transaction", t.ID, err.Error())
+			continue
+		}
+		_, err := tx.Exec(
+			"INSERT INTO transactions (budget_id, amount, note, payee, date, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			budgetID, t.Amount, t.Note, nullString(t.Payee), t.Date, createdAt(t.CreatedAt),
+		)
+		if err != nil {
+			conflict("transaction", t.ID, err.Error())
+			continue
+		}
+		report.Created["transactions"]++
+	}
+
+	goalIDs := map[int64]int64{}
+	for _, g := range pa.Goals {
+		nodeID, ok := remapOptional(g.NodeID, nodeIDs)
+		if !ok {
+			conflict("goal", g.ID, "linked node was not imported; goal left unlinked")
+		}
+		if err := s.crypt.EncryptAll(&g.Name); err != nil {
+			conflict("goal", g.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO goals (profile_id, node_id, name, target, current, deadline, priority, color, created_at,
+				rollover, completed_at, archived_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, profileID, nodeID, g.Name, g.Target, g.Current, g.Deadline, g.Priority, g.Color, createdAt(g.CreatedAt),
+			g.Rollover, nullTime(g.CompletedAt), nullTime(g.ArchivedAt))
+		if err != nil {
+			conflict("goal", g.ID, err.Error())
+			continue
+		}
+		goalID, _ := result.LastInsertId()
+		goalIDs[g.ID] = goalID
+		report.Created["goals"]++
+
+		// Archives written before milestones existed get the defaults, stamped
+		// the way the migration stamps existing goals
+		if len(g.Milestones) == 0 {
+			if err := AddDefaultMilestones(tx, goalID); err != nil {
+				return 0, err
+			}
+			if err := s.settleGoal(tx, goalID, time.Now().Format("2006-01-02")); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		for _, m := range g.Milestones {
+			var percent, amount any
+			if m.Percent > 0 {
+				percent = m.Percent
+			} else {
+				amount = m.Amount
+			}
+			_, err := tx.Exec(
+				"INSERT INTO goal_milestones (goal_id, percent, amount, reached_at, created_at) VALUES (?, ?, ?, ?, ?)",
+				goalID, percent, amount, nullTime(m.ReachedAt), createdAt(m.CreatedAt),
+			)
+			if err != nil {
+				conflict("goal_milestone", m.ID, err.Error())
+				continue
+			}
+			report.Created["goal_milestones"]++
+		}
+	}
+
+	for _, t := range pa.GoalTransactions {
+		goalID, ok := goalIDs[t.GoalID]
+		if !ok {
+			conflict("goal_transaction", t.ID, "references a goal that was not imported")
+			continue
+		}
+		// Archives from before kinds were recorded are classified by note
+		kind := t.Kind
+		if kind == "" {
+			kind = GoalTxKind(t.Note, t.FlowID)
+		}
+		flowID, _ := remapOptional(t.FlowID, flowIDs)
+		if err := s.crypt.EncryptAll(&t.Note); err != nil {
+			conflict("goal_transaction", t.ID, err.Error())
+			continue
+		}
+		_, err := tx.Exec(
+			"INSERT INTO goal_transactions (goal_id, amount, note, date, created_at, flow_id, kind) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			goalID, t.Amount, t.Note, t.Date, createdAt(t.CreatedAt), flowID, kind,
+		)
+		if err != nil {
+			conflict("goal_transaction", t.ID, err.Error())
+			continue
+		}
+		report.Created["goal_transactions"]++
+	}
+
+	expenseIDs := map[int64]int64{}
+	for _, e := range pa.Expenses {
+		if err := s.crypt.EncryptAll(&e.Name); err != nil {
+			conflict("expense", e.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO expenses (profile_id, name, amount, period, category, type, flag, next_due, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, profileID, e.Name, e.Amount, e.Period, e.Category, e.Type, nullString(e.Flag), nullString(e.NextDue), createdAt(e.CreatedAt))
+		if err != nil {
+			conflict("expense", e.ID, err.Error())
+			continue
+		}
+		expenseIDs[e.ID], _ = result.LastInsertId()
+		report.Created["expenses"]++
+	}
+
+	for _, f := range pa.SinkingFunds {
+		expenseID, okExpense := expenseIDs[f.ExpenseID]
+		nodeID, okNode := nodeIDs[f.NodeID]
+		if !okExpense || !okNode {
+			conflict("sinking_fund", f.ID, "references an expense or node that was not imported")
+			continue
+		}
+		_, err := tx.Exec(
+			"INSERT INTO sinking_funds (profile_id, expense_id, node_id, last_paid, created_at) VALUES (?, ?, ?, ?, ?)",
+			profileID, expenseID, nodeID, nullString(f.LastPaid), createdAt(f.CreatedAt),
+		)
+		if err != nil {
+			conflict("sinking_fund", f.ID, err.Error())
+			continue
+		}
+		report.Created["sinking_funds"]++
+	}
+
+	holdingIDs := map[int64]int64{}
+	for _, h := range pa.Holdings {
+		nodeID, ok := nodeIDs[h.NodeID]
+		if !ok {
+			conflict("holding", h.ID, "references a node that was not imported")
+			continue
+		}
+		result, err := tx.Exec(
+			"INSERT INTO holdings (node_id, symbol, asset_class, created_at) VALUES (?, ?, ?, ?)",
+			nodeID, NormalizeSymbol(h.Symbol), h.AssetClass, createdAt(h.CreatedAt),
+		)
+		if err != nil {
+			conflict("holding", h.ID, err.Error())
+			continue
+		}
+		holdingIDs[h.ID], _ = result.LastInsertId()
+		report.Created["holdings"]++
+	}
+
+	for _, t := range pa.HoldingTransactions {
+		holdingID, ok := holdingIDs[t.HoldingID]
+		if !ok {
+			conflict("holding_transaction", t.ID, "references a holding that was not imported")
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO holding_transactions (holding_id, type, quantity, price, amount, date, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, holdingID, t.Type, t.Quantity, t.Price, t.Amount, t.Date, createdAt(t.CreatedAt))
+		if err != nil {
+			conflict("holding_transaction", t.ID, err.Error())
+			continue
+		}
+		report.Created["holding_transactions"]++
+	}
+
+	for _, p := range pa.Prices {
+		if err := ValidatePrice(&p); err != nil {
+			conflict("price", 0, fmt.Sprintf("%s %s: %v", p.Symbol, p.Date, err))
+			continue
+		}
+		_, err := tx.Exec(
+			"INSERT OR REPLACE INTO prices (profile_id, symbol, date, price) VALUES (?, ?, ?, ?)",
+			profileID, p.Symbol, p.Date, p.Price,
+		)
+		if err != nil {
+			conflict("price", 0, err.Error())
+			continue
+		}
+		report.Created["prices"]++
+	}
+
+	for _, sc := range pa.Scenarios {
+		if err := s.crypt.EncryptAll(&sc.Name, &sc.Description); err != nil {
+			conflict("scenario", sc.ID, err.Error())
+			continue
+		}
+		result, err := tx.Exec(
+			"INSERT INTO scenarios (profile_id, name, description, created_at) VALUES (?, ?, ?, ?)",
+			profileID, sc.Name, nullString(sc.Description), createdAt(sc.CreatedAt),
+		)
+		if err != nil {
+			conflict("scenario", sc.ID, err.Error())
+			continue
+		}
+		scenarioID, _ := result.LastInsertId()
+		report.Created["scenarios"]++
+
+		for _, c := range sc.Changes {
+			// Changes whose node or flow wasn't imported keep NULL references,
+			// so they come back stale just as they were in the source
+			var nodeID, flowID, fromID, toID any
+			resolved := true
+			switch c.Kind {
+			case ChangeNodeAmount:
+				nodeID, resolved = remapOptional(c.NodeID, nodeIDs)
+			case ChangeFlowAmount, ChangeRemoveFlow:
+				flowID, resolved = remapOptional(c.FlowID, flowIDs)
+			case ChangeAddFlow:
+				var okFrom, okTo bool
+				fromID, okFrom = remapOptional(c.FromNodeID, nodeIDs)
+				toID, okTo = remapOptional(c.ToNodeID, nodeIDs)
+				resolved = okFrom && okTo
+			}
+			if !resolved || c.Stale {
+				conflict("scenario_change", c.ID, "references a node or flow that was not imported; change left stale")
+			}
+			if err := s.crypt.EncryptAll(&c.Label); err != nil {
+				conflict("scenario_change", c.ID, err.Error())
+				continue
+			}
+			_, err := tx.Exec(`
+				INSERT INTO scenario_changes (scenario_id, kind, node_id, flow_id, from_node_id, to_node_id, amount, label, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, scenarioID, c.Kind, nodeID, flowID, fromID, toID, c.Amount, nullString(c.Label), createdAt(c.CreatedAt))
+			if err != nil {
+				conflict("scenario_change", c.ID, err.Error())
+				continue
+			}
+			report.Created["scenario_changes"]++
+		}
+	}
+
+	return profileID, nil
+}
+
+// remapOptional translates a nullable foreign key. ok is false when the
+// source had a reference that could not be resolved.
+func remapOptional(id int64, ids map[int64]int64) (interface{}, bool) {
+	if id == 0 {
+		return nil, true
+	}
+	if newID, ok := ids[id]; ok {
+		return newID, true
+	}
+	return nil, false
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	candidate := name + " (imported)"
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (imported %d)", name, i)
+	}
+	return candidate
+}
+
+// createdAt formats timestamps the way CURRENT_TIMESTAMP stores them
+func createdAt(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// nullTime formats an optional timestamp like createdAt, or NULL
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return createdAt(*t)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Budget pace statuses
+const (
+	PaceOnTrack   = "on_pace"
+	PaceOver      = "over_pace" // Projected to overspend by the end of the period
+	PaceOverspent = "overspent" // Already over budget
+)
+
+// BudgetPeriod returns the first day of the budget period containing now
+// and the first day of the next one. Weeks start on Monday.
+func BudgetPeriod(period string, now time.Time) (start, end time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "weekly":
+		start = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case "yearly":
+		start = time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	default:
+		start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// AssessBudgets totals each budget's spending over its own current period
+// (week, month or year) and works out its pace: what should have been spent
+// by today if the budget were spent evenly, the daily burn rate so far, the
+// total that rate reaches by the end of the period, and the days until the
+// budget runs out at that rate. Today counts as an elapsed day.
+func AssessBudgets(budgets []models.Budget, transactions []models.Transaction, now time.Time) []models.Budget {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	assessed := make([]models.Budget, 0, len(budgets))
+	for _, b := range budgets {
+		start, end := BudgetPeriod(b.Period, now)
+		from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+		b.Spent = 0
+		for _, t := range transactions {
+			if t.BudgetID == b.ID && t.Date >= from && t.Date < to {
+				b.Spent += t.Amount
+			}
+		}
+		b.Spent = RoundCents(b.Spent)
+		b.Remaining = RoundCents(b.Budgeted - b.Spent)
+		if b.Budgeted > 0 {
+			b.Percentage = (b.Spent / b.Budgeted) * 100
+		}
+
+		totalDays := end.Sub(start).Hours() / 24
+		elapsedDays := today.Sub(start).Hours()/24 + 1
+		b.PeriodStart = from
+		b.PeriodEnd = end.AddDate(0, 0, -1).Format("2006-01-02")
+		b.DaysRemaining = int(totalDays - elapsedDays)
+
+		b.ExpectedSpend = RoundCents(b.Budgeted * elapsedDays / totalDays)
+		b.DailyBurn = RoundCents(b.Spent / elapsedDays)
+		b.ProjectedSpend = RoundCents(b.Spent / elapsedDays * totalDays)
+
+		switch {
+		case b.Remaining <= 0 && b.Spent > 0:
+			days := 0
+			b.DaysToExhaustion = &days
+		case b.Spent > 0:
+			days := int(math.Floor(b.Remaining / (b.Spent / elapsedDays)))
+			b.DaysToExhaustion = &days
+		}
+
+		switch {
+		case b.Spent > b.Budgeted:
+			b.PaceStatus = PaceOverspent
+		case b.ProjectedSpend > b.Budgeted:
+			b.PaceStatus = PaceOver
+		default:
+			b.PaceStatus = PaceOnTrack
+		}
+		b.ProjectedOverspend = b.ProjectedSpend > b.Budgeted
+		if b.ProjectedOverspend {
+			b.Overspend = RoundCents(b.ProjectedSpend - b.Budgeted)
+		}
+
+		assessed = append(assessed, b)
+	}
+
+	return assessed
+}
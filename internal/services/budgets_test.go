@@ -0,0 +1,156 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+func TestAssessBudgets(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name   string
+		budget models.Budget
+		txs    []models.Transaction
+		now    string
+
+		start, end    string
+		spent         float64
+		daysRemaining int
+		expected      float64
+		burn          float64
+		projected     float64
+		exhaustion    *int
+		overspend     float64
+		status        string
+	}{
+		{
+			// Wednesday: Monday through today is three of seven days
+			name:   "weekly",
+			budget: models.Budget{ID: 1, Budgeted: 70, Period: "weekly"},
+			txs: []models.Transaction{
+				{BudgetID: 1, Amount: 15, Date: "2026-10-11"}, // Sunday before
+				{BudgetID: 1, Amount: 25, Date: "2026-10-12"},
+				{BudgetID: 1, Amount: 15, Date: "2026-10-14"},
+				{BudgetID: 2, Amount: 99, Date: "2026-10-14"}, // Another budget
+				{BudgetID: 1, Amount: 10, Date: "2026-10-19"}, // Next Monday
+			},
+			now:   "2026-10-14",
+			start: "2026-10-12", end: "2026-10-18",
+			spent:         40,
+			daysRemaining: 4,
+			expected:      30,
+			burn:          13.33,
+			projected:     93.33,
+			exhaustion:    intPtr(2),
+			overspend:     23.33,
+			status:        PaceOver,
+		},
+		{
+			name:   "weekly on a Sunday is the last day",
+			budget: models.Budget{ID: 1, Budgeted: 70, Period: "weekly"},
+			txs:    []models.Transaction{{BudgetID: 1, Amount: 35, Date: "2026-10-12"}},
+			now:    "2026-10-18",
+			start:  "2026-10-12", end: "2026-10-18",
+			spent:      35,
+			expected:   70,
+			burn:       5,
+			projected:  35,
+			exhaustion: intPtr(7),
+			status:     PaceOnTrack,
+		},
+		{
+			name:   "yearly",
+			budget: models.Budget{ID: 1, Budgeted: 3650, Period: "yearly"},
+			txs: []models.Transaction{
+				{BudgetID: 1, Amount: 200, Date: "2025-12-31"},
+				{BudgetID: 1, Amount: 500, Date: "2026-01-01"},
+				{BudgetID: 1, Amount: 500, Date: "2026-07-02"},
+			},
+			now:   "2026-07-02",
+			start: "2026-01-01", end: "2026-12-31",
+			spent:         1000,
+			daysRemaining: 182,
+			expected:      1830,
+			burn:          5.46,
+			projected:     1994.54,
+			exhaustion:    intPtr(484),
+			status:        PaceOnTrack,
+		},
+		{
+			name:   "monthly overspent",
+			budget: models.Budget{ID: 1, Budgeted: 300, Period: "monthly"},
+			txs:    []models.Transaction{{BudgetID: 1, Amount: 320, Date: "2026-02-03"}},
+			now:    "2026-02-07",
+			start:  "2026-02-01", end: "2026-02-28",
+			spent:         320,
+			daysRemaining: 21,
+			expected:      75,
+			burn:          45.71,
+			projected:     1280,
+			exhaustion:    intPtr(0),
+			overspend:     980,
+			status:        PaceOverspent,
+		},
+		{
+			name:   "zero budget with nothing spent",
+			budget: models.Budget{ID: 1, Period: "monthly"},
+			now:    "2026-02-07",
+			start:  "2026-02-01", end: "2026-02-28",
+			daysRemaining: 21,
+			status:        PaceOnTrack,
+		},
+		{
+			name:   "zero budget with spending",
+			budget: models.Budget{ID: 1, Period: "monthly"},
+			txs:    []models.Transaction{{BudgetID: 1, Amount: 14, Date: "2026-02-01"}},
+			now:    "2026-02-07",
+			start:  "2026-02-01", end: "2026-02-28",
+			spent:         14,
+			daysRemaining: 21,
+			burn:          2,
+			projected:     56,
+			exhaustion:    intPtr(0),
+			overspend:     56,
+			status:        PaceOverspent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessed := AssessBudgets([]models.Budget{tt.budget}, tt.txs, mustDate(tt.now).Add(15*time.Hour))
+			if len(assessed) != 1 {
+				t.Fatalf("got %d budgets, want 1", len(assessed))
+			}
+			b := assessed[0]
+
+			if b.PeriodStart != tt.start || b.PeriodEnd != tt.end {
+				t.Errorf("period = %s to %s, want %s to %s", b.PeriodStart, b.PeriodEnd, tt.start, tt.end)
+			}
+			if b.Spent != tt.spent || b.Remaining != RoundCents(tt.budget.Budgeted-tt.spent) {
+				t.Errorf("spent %v, remaining %v; want %v, %v", b.Spent, b.Remaining, tt.spent, RoundCents(tt.budget.Budgeted-tt.spent))
+			}
+			if b.DaysRemaining != tt.daysRemaining {
+				t.Errorf("days remaining = %d, want %d", b.DaysRemaining, tt.daysRemaining)
+			}
+			if b.ExpectedSpend != tt.expected || b.DailyBurn != tt.burn || b.ProjectedSpend != tt.projected {
+				t.Errorf("expected %v, burn %v, projected %v; want %v, %v, %v",
+					b.ExpectedSpend, b.DailyBurn, b.ProjectedSpend, tt.expected, tt.burn, tt.projected)
+			}
+			switch {
+			case tt.exhaustion == nil && b.DaysToExhaustion != nil:
+				t.Errorf("days to exhaustion = %d, want none", *b.DaysToExhaustion)
+			case tt.exhaustion != nil && (b.DaysToExhaustion == nil || *b.DaysToExhaustion != *tt.exhaustion):
+				t.Errorf("days to exhaustion = %v, want %d", b.DaysToExhaustion, *tt.exhaustion)
+			}
+			if b.ProjectedOverspend != (tt.overspend > 0) || b.Overspend != tt.overspend {
+				t.Errorf("projected overspend %v of %v, want %v", b.ProjectedOverspend, b.Overspend, tt.overspend)
+			}
+			if b.PaceStatus != tt.status {
+				t.Errorf("status = %s, want %s", b.PaceStatus, tt.status)
+			}
+		})
+	}
+}
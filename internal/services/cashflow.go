@@ -0,0 +1,204 @@
+package services
+
+import (
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Cash-flow calendar event kinds
+const (
+	CashFlowPayday   = "payday"   // Income paid into the account
+	CashFlowBill     = "bill"     // An expense falling due
+	CashFlowPayment  = "payment"  // A flow out to spending or debt
+	CashFlowTransfer = "transfer" // A flow to or from another account, savings or goal
+)
+
+// MaxCashFlowDays caps the days in one cash-flow calendar
+const MaxCashFlowDays = 366
+
+// tracksCashFlow reports whether a node type gets a day-by-day balance
+func tracksCashFlow(nodeType string) bool {
+	return nodeType == "account" || nodeType == "savings"
+}
+
+// BuildCashFlowCalendar projects every account and savings node's balance
+// day by day over the days starting today, from their current balances.
+// Recurring flows land monthly on their anchor date (or the day they were
+// created); expenses land on their next_due date and every period after.
+// A bill is paid from its sinking fund's node if it has one and otherwise
+// from the first account node, and bills without a due date are left out.
+// Runs of days below floor come back as warnings.
+func BuildCashFlowCalendar(data *ProfileData, now time.Time, days int, floor float64) models.CashFlowCalendar {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, days)
+
+	cal := models.CashFlowCalendar{
+		From:     from.Format("2006-01-02"),
+		To:       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Floor:    floor,
+		Accounts: []models.AccountCashFlow{},
+		Warnings: []models.LowBalanceWarning{},
+	}
+
+	types := make(map[int64]string, len(data.Nodes))
+	var primary int64
+	for _, n := range data.Nodes {
+		types[n.ID] = n.Type
+		if n.Type == "account" && primary == 0 {
+			primary = n.ID
+		}
+	}
+
+	// events[node][date]
+	events := map[int64]map[string][]models.CashFlowEvent{}
+	add := func(nodeID int64, date time.Time, e models.CashFlowEvent) {
+		if !tracksCashFlow(types[nodeID]) {
+			return
+		}
+		if events[nodeID] == nil {
+			events[nodeID] = map[string][]models.CashFlowEvent{}
+		}
+		day := date.Format("2006-01-02")
+		events[nodeID][day] = append(events[nodeID][day], e)
+	}
+
+	for _, f := range data.Flows {
+		if !f.IsRecurring || f.Amount <= 0 {
+			continue
+		}
+		anchor := time.Date(f.CreatedAt.Year(), f.CreatedAt.Month(), f.CreatedAt.Day(), 0, 0, 0, 0, time.UTC)
+		if t, err := time.Parse("2006-01-02", f.AnchorDate); err == nil {
+			anchor = t
+		}
+
+		in, out := CashFlowTransfer, CashFlowTransfer
+		switch fromType, toType := types[f.FromNodeID], types[f.ToNodeID]; {
+		case fromType == "income":
+			in = CashFlowPayday
+		case toType == "expense" || toType == "budget" || IsLiability(toType):
+			out = CashFlowPayment
+		}
+
+		for _, day := range occurrences(anchor, 0, 1, from, to) {
+			add(f.ToNodeID, day, models.CashFlowEvent{Kind: in, Label: f.Label, Amount: f.Amount, FlowID: f.ID})
+			add(f.FromNodeID, day, models.CashFlowEvent{Kind: out, Label: f.Label, Amount: -f.Amount, FlowID: f.ID})
+		}
+	}
+
+	funds := map[int64]int64{}
+	for _, f := range data.SinkingFunds {
+		funds[f.ExpenseID] = f.NodeID
+	}
+	for _, e := range data.Expenses {
+		due, err := time.Parse("2006-01-02", e.NextDue)
+		if err != nil || e.Amount == 0 {
+			continue
+		}
+		payer, ok := funds[e.ID]
+		if !ok {
+			payer = primary
+		}
+
+		var dates []time.Time
+		switch e.Period {
+		case "weekly":
+			dates = occurrences(due, 7, 0, from, to)
+		case "quarterly":
+			dates = occurrences(due, 0, 3, from, to)
+		case "annual":
+			dates = occurrences(due, 0, 12, from, to)
+		default:
+			dates = occurrences(due, 0, 1, from, to)
+		}
+		for _, day := range dates {
+			add(payer, day, models.CashFlowEvent{Kind: CashFlowBill, Label: e.Name, Amount: -e.Amount, ExpenseID: e.ID})
+		}
+	}
+
+	for _, n := range data.Nodes {
+		if !tracksCashFlow(n.Type) {
+			continue
+		}
+		account := models.AccountCashFlow{
+			NodeID: n.ID,
+			Label:  n.Label,
+			Type:   n.Type,
+			Start:  RoundCents(n.Balance),
+			Days:   make([]models.CashFlowDay, 0, days),
+		}
+
+		balance := n.Balance
+		var warning *models.LowBalanceWarning
+		for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+			day := models.CashFlowDay{Date: d.Format("2006-01-02"), Events: events[n.ID][d.Format("2006-01-02")]}
+			for _, e := range day.Events {
+				if e.Amount > 0 {
+					day.In += e.Amount
+				} else {
+					day.Out -= e.Amount
+				}
+				day.Payday = day.Payday || e.Kind == CashFlowPayday
+				day.Bill = day.Bill || e.Kind == CashFlowBill
+			}
+			balance += day.In - day.Out
+			day.In, day.Out = RoundCents(day.In), RoundCents(day.Out)
+			day.Balance = RoundCents(balance)
+			day.BelowFloor = day.Balance < floor
+
+			if day.Balance < account.Lowest || account.LowestDate == "" {
+				account.Lowest, account.LowestDate = day.Balance, day.Date
+			}
+
+			switch {
+			case day.BelowFloor && warning == nil:
+				warning = &models.LowBalanceWarning{NodeID: n.ID, Label: n.Label, From: day.Date, Lowest: day.Balance, LowestDate: day.Date}
+				fallthrough
+			case day.BelowFloor:
+				warning.To = day.Date
+				warning.Days++
+				if day.Balance < warning.Lowest {
+					warning.Lowest, warning.LowestDate = day.Balance, day.Date
+				}
+			case warning != nil:
+				cal.Warnings = append(cal.Warnings, *warning)
+				warning = nil
+			}
+
+			account.Days = append(account.Days, day)
+		}
+		if warning != nil {
+			cal.Warnings = append(cal.Warnings, *warning)
+		}
+
+		account.End = RoundCents(balance)
+		cal.Accounts = append(cal.Accounts, account)
+	}
+
+	return cal
+}
+
+// occurrences lists the dates in [from, to) that fall every days days or
+// every months months from anchor
+func occurrences(anchor time.Time, days, months int, from, to time.Time) []time.Time {
+	var dates []time.Time
+	k := 0
+	// Skip straight to the period before from
+	if gap := from.Sub(anchor).Hours() / 24; gap > 0 {
+		if days > 0 {
+			k = int(gap)/days - 1
+		} else {
+			k = ((from.Year()-anchor.Year())*12+int(from.Month()-anchor.Month()))/months - 1
+		}
+		k = max(k, 0)
+	}
+	for ; ; k++ {
+		d := AddMonths(anchor, k*months).AddDate(0, 0, k*days)
+		if !d.Before(to) {
+			return dates
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// BalanceContribution is the balance history source for goal contributions
+const BalanceContribution = "contribution"
+
+// Goal transaction kinds. Only contributions, including those posted by
+// flows, are newly saved money; the rest restate or move what a goal holds.
+const (
+	GoalTxContribution = "contribution"
+	GoalTxOpening      = "opening"    // The amount a goal was created with
+	GoalTxAdjustment   = "adjustment" // A hand edit of the goal's current amount
+	GoalTxRollover     = "rollover"   // Excess moved from a completed goal
+	GoalTxReconcile    = "reconcile"  // A repair by ReconcileGoals
+)
+
+// ApplyGoalContribution records money paid into a goal (or taken out, if
+// negative) and moves the goal's current amount and its node's balance by
+// the same amount. Every change to goal money goes through here or
+// RevertGoalContribution, which keeps goals.current, the node balance and
+// the sum of goal_transactions equal, and settles the goal's milestones,
+// completion and rollover. The goal must belong to the caller's profile;
+// t.ID and t.CreatedAt are filled in, and an empty t.Kind is a contribution.
+func (s *Store) ApplyGoalContribution(tx *sql.Tx, t models.GoalTransaction) (models.GoalTransaction, error) {
+	note := t.Note
+	if err := s.crypt.EncryptAll(&note); err != nil {
+		return t, err
+	}
+
+	var flowID any
+	if t.FlowID != 0 {
+		flowID = t.FlowID
+	}
+	if t.Kind == "" {
+		t.Kind = GoalTxContribution
+	}
+	result, err := tx.Exec(`
+		INSERT INTO goal_transactions (goal_id, amount, note, date, flow_id, kind)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, t.GoalID, t.Amount, note, t.Date, flowID, t.Kind)
+	if err != nil {
+		return t, err
+	}
+	t.ID, _ = result.LastInsertId()
+	t.CreatedAt = time.Now()
+
+	if err := moveGoalMoney(tx, t.GoalID, t.Amount); err != nil {
+		return t, err
+	}
+	return t, s.settleGoal(tx, t.GoalID, t.Date)
+}
+
+// RevertGoalContribution deletes a goal transaction and moves the goal and
+// its node back. Returns sql.ErrNoRows if the goal has no such transaction.
+func (s *Store) RevertGoalContribution(tx *sql.Tx, goalID, txID int64) error {
+	var amount float64
+	err := tx.QueryRow("SELECT amount FROM goal_transactions WHERE id = ? AND goal_id = ?", txID, goalID).Scan(&amount)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM goal_transactions WHERE id = ?", txID); err != nil {
+		return err
+	}
+	if err := moveGoalMoney(tx, goalID, -amount); err != nil {
+		return err
+	}
+	return s.settleGoal(tx, goalID, time.Now().Format("2006-01-02"))
+}
+
+// moveGoalMoney adds amount to a goal and to its node's balance
+func moveGoalMoney(tx *sql.Tx, goalID int64, amount float64) error {
+	if _, err := tx.Exec("UPDATE goals SET current = current + ? WHERE id = ?", amount, goalID); err != nil {
+		return err
+	}
+
+	var nodeID sql.NullInt64
+	if err := tx.QueryRow("SELECT node_id FROM goals WHERE id = ?", goalID).Scan(&nodeID); err != nil {
+		return err
+	}
+	if !nodeID.Valid {
+		return nil
+	}
+
+	result, err := tx.Exec("UPDATE nodes SET balance = balance + ? WHERE id = ?", amount, nodeID.Int64)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// The node is gone; the goal still tracks its own money
+		return nil
+	}
+
+	var balance float64
+	if err := tx.QueryRow("SELECT balance FROM nodes WHERE id = ?", nodeID.Int64).Scan(&balance); err != nil {
+		return err
+	}
+	return RecordBalance(tx, nodeID.Int64, balance, BalanceContribution)
+}
+
+// AddGoalContribution applies a single contribution in its own transaction
+func (s *Store) AddGoalContribution(t models.GoalTransaction) (models.GoalTransaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return t, err
+	}
+	defer tx.Rollback()
+
+	if t, err = s.ApplyGoalContribution(tx, t); err != nil {
+		return t, err
+	}
+	return t, tx.Commit()
+}
+
+// DeleteGoalContribution reverts a single contribution in its own transaction
+func (s *Store) DeleteGoalContribution(goalID, txID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.RevertGoalContribution(tx, goalID, txID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GoalTxKind guesses the kind of a goal transaction recorded before kinds
+// were stored, from the notes the server has always written for each one
+func GoalTxKind(note string, flowID int64) string {
+	switch {
+	case flowID != 0:
+		return GoalTxContribution
+	case note == "Opening balance":
+		return GoalTxOpening
+	case note == "Adjustment":
+		return GoalTxAdjustment
+	case note == "Reconciliation":
+		return GoalTxReconcile
+	case strings.HasPrefix(note, "Rolled over "):
+		return GoalTxRollover
+	}
+	return GoalTxContribution
+}
+
+// ClassifyGoalTransactions sets the kind of every goal transaction written
+// before kinds existed and returns how many it set. Notes may be encrypted,
+// so this runs in Go rather than as a migration.
+func (s *Store) ClassifyGoalTransactions() (int, error) {
+	rows, err := s.db.Query("SELECT id, note, COALESCE(flow_id, 0) FROM goal_transactions WHERE kind IS NULL")
+	if err != nil {
+		return 0, err
+	}
+	kinds := map[int64]string{}
+	for rows.Next() {
+		var id, flowID int64
+		var note sql.NullString
+		if err := rows.Scan(&id, &note, &flowID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if err := s.crypt.DecryptAll(&note.String); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		kinds[id] = GoalTxKind(note.String, flowID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for id, kind := range kinds {
+		if _, err := tx.Exec("UPDATE goal_transactions SET kind = ? WHERE id = ?", kind, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(kinds), tx.Commit()
+}
+
+// AnchorFlow sets the day a flow recurs monthly from. Occurrences before
+// today count as already contributed, so moving the anchor never back-posts.
+func AnchorFlow(db execer, flowID int64, anchor string, now time.Time) error {
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	_, err := db.Exec(`
+		UPDATE flows SET anchor_date = ?, contributed_through = MAX(COALESCE(contributed_through, ?), ?)
+		WHERE id = ?
+	`, anchor, yesterday, yesterday, flowID)
+	return err
+}
+
+// PostGoalContributions turns recurring flows into goal nodes into goal
+// contributions, one per flow on each monthly anniversary of its anchor
+// date that has passed. A flow seen for the first time is anchored today,
+// so nothing is back-posted. Returns the contributions written.
+func (s *Store) PostGoalContributions(now time.Time) (int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	rows, err := s.db.Query(`
+		SELECT f.id FROM flows f
+		JOIN nodes n ON n.id = f.to_node_id AND n.type = 'goal'
+		JOIN goals g ON g.node_id = n.id
+		WHERE f.is_recurring = 1 AND f.amount > 0
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, id := range ids {
+		n, err := s.postFlowContributions(id, today)
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+
+	return written, nil
+}
+
+func (s *Store) postFlowContributions(flowID int64, today time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Re-read inside the transaction so an edit to the flow isn't lost
+	var goalID int64
+	var amount float64
+	var label, anchor, through sql.NullString
+	err = tx.QueryRow(`
+		SELECT g.id, f.amount, f.label, f.anchor_date, f.contributed_through
+		FROM flows f JOIN goals g ON g.node_id = f.to_node_id
+		WHERE f.id = ?
+	`, flowID).Scan(&goalID, &amount, &label, &anchor, &through)
+	if err != nil {
+		return 0, err
+	}
+
+	if !anchor.Valid {
+		day := today.Format("2006-01-02")
+		if _, err := tx.Exec("UPDATE flows SET anchor_date = ?, contributed_through = ? WHERE id = ?", day, day, flowID); err != nil {
+			return 0, err
+		}
+		return 0, tx.Commit()
+	}
+
+	start, err := time.Parse("2006-01-02", DateOnly(anchor.String))
+	if err != nil {
+		return 0, err
+	}
+	done := start
+	if through.Valid {
+		if done, err = time.Parse("2006-01-02", DateOnly(through.String)); err != nil {
+			return 0, err
+		}
+	}
+
+	// The flow's label is stored encrypted; the note is re-encrypted on insert
+	note := label.String
+	if err := s.crypt.DecryptAll(&note); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for k := 0; ; k++ {
+		due := AddMonths(start, k)
+		if due.After(today) {
+			break
+		}
+		if !due.After(done) {
+			continue
+		}
+		_, err := s.ApplyGoalContribution(tx, models.GoalTransaction{
+			GoalID: goalID,
+			Amount: amount,
+			Note:   note,
+			Date:   due.Format("2006-01-02"),
+			FlowID: flowID,
+			Kind:   GoalTxContribution,
+		})
+		if err != nil {
+			return 0, err
+		}
+		written++
+	}
+
+	if _, err := tx.Exec("UPDATE flows SET contributed_through = ? WHERE id = ?", today.Format("2006-01-02"), flowID); err != nil {
+		return 0, err
+	}
+	return written, tx.Commit()
+}
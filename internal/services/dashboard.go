@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// recentActivityLimit caps the transactions returned on the dashboard
+const recentActivityLimit = 10
+
+// IsLiability reports whether a node type is money owed rather than held
+func IsLiability(nodeType string) bool {
+	switch nodeType {
+	case "liability", "credit_card", "loan":
+		return true
+	}
+	return false
+}
+
+// IsAsset reports whether a node type's balance counts towards net worth.
+// Goal nodes are excluded: their balance mirrors money held elsewhere.
+func IsAsset(nodeType string) bool {
+	switch nodeType {
+	case "account", "savings", "investment":
+		return true
+	}
+	return false
+}
+
+// monthlyCashFlow totals the planned income, the flows into expense and
+// budget nodes, and the debt payments
+func monthlyCashFlow(data *ProfileData) (income, expenses, debt float64) {
+	types := make(map[int64]string, len(data.Nodes))
+	for _, n := range data.Nodes {
+		types[n.ID] = n.Type
+		if n.Type == "income" {
+			income += n.Amount
+		}
+	}
+
+	for _, f := range data.Flows {
+		if to := types[f.ToNodeID]; to == "expense" || to == "budget" {
+			expenses += f.Amount
+		}
+	}
+
+	return income, expenses, DebtBudget(data)
+}
+
+// BuildDashboard aggregates a profile's monthly cash flow and balance sheet.
+// Flows into liability nodes are debt payments, not spending: they reduce
+// what is owed, so they lower the surplus but never count as expenses.
+func BuildDashboard(data *ProfileData, now time.Time) models.DashboardResponse {
+	resp := models.DashboardResponse{
+		Nodes:          data.Nodes,
+		Flows:          data.Flows,
+		BudgetSummary:  []models.Budget{},
+		GoalProgress:   []models.Goal{},
+		RecentActivity: []models.Transaction{},
+	}
+
+	for _, n := range data.Nodes {
+		switch {
+		case IsAsset(n.Type):
+			resp.TotalAssets += n.Balance
+		case IsLiability(n.Type):
+			resp.TotalLiabilities += n.Balance
+		}
+	}
+
+	resp.TotalIncome, resp.TotalExpenses, resp.DebtPayments = monthlyCashFlow(data)
+	resp.NetSurplus = resp.TotalIncome - resp.TotalExpenses - resp.DebtPayments
+	resp.NetWorth = resp.TotalAssets - resp.TotalLiabilities
+
+	resp.BudgetSummary = AssessBudgets(data.Budgets, data.Transactions, now)
+
+	resp.GoalProgress = ActiveGoals(assessGoals(data, now, resp.NetSurplus))
+
+	// Transactions are loaded oldest first
+	recent := append([]models.Transaction{}, data.Transactions...)
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Date > recent[j].Date })
+	if len(recent) > recentActivityLimit {
+		recent = recent[:recentActivityLimit]
+	}
+	resp.RecentActivity = recent
+
+	return resp
+}
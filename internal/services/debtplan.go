@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Debt payoff strategies
+const (
+	StrategySnowball  = "snowball"  // Smallest balance first
+	StrategyAvalanche = "avalanche" // Highest interest rate first
+	StrategyCustom    = "custom"    // Caller-supplied order
+)
+
+var ErrBudgetBelowMinimums = errors.New("monthly debt budget is less than the minimum payments")
+
+// debt is a liability being paid down during a simulation
+type debt struct {
+	node     models.Node
+	minimum  float64
+	balance  float64
+	interest float64 // Accrued so far
+	paidOff  int     // Month number, 0 while outstanding
+}
+
+// DebtBudget is what the profile currently puts towards its debts each
+// month: the flows into each outstanding liability node, or its minimum
+// payment if nothing flows into it yet. Paid-off liabilities don't count,
+// even with a minimum payment or a leftover flow.
+func DebtBudget(data *ProfileData) float64 {
+	paid := map[int64]float64{}
+	for _, f := range data.Flows {
+		paid[f.ToNodeID] += f.Amount
+	}
+
+	budget := 0.0
+	for _, n := range data.Nodes {
+		if !IsLiability(n.Type) || n.Balance <= 0 {
+			continue
+		}
+		if amount, ok := paid[n.ID]; ok {
+			budget += amount
+		} else {
+			budget += ScheduledPayment(n)
+		}
+	}
+	return RoundCents(budget)
+}
+
+// PlanDebtPayoff simulates paying every outstanding liability with a fixed
+// monthly budget. Each month every debt gets its minimum payment and the
+// rest of the budget (including minimums freed by paid-off debts) goes to
+// the first outstanding debt in strategy order. order is only used by the
+// custom strategy; debts it doesn't mention follow in snowball order.
+func PlanDebtPayoff(nodes []models.Node, budget float64, strategy string, order []int64, first time.Time) (models.DebtPlan, error) {
+	plan := models.DebtPlan{Strategy: strategy, Payoffs: []models.DebtPayoff{}, Schedule: []models.DebtPlanMonth{}}
+
+	var debts []*debt
+	minimums := 0.0
+	for _, n := range nodes {
+		if !IsLiability(n.Type) || n.Balance <= 0 {
+			continue
+		}
+		d := &debt{node: n, minimum: ScheduledPayment(n), balance: n.Balance}
+		minimums += d.minimum
+		debts = append(debts, d)
+	}
+	if len(debts) == 0 {
+		return plan, nil
+	}
+	if budget < RoundCents(minimums) {
+		return plan, ErrBudgetBelowMinimums
+	}
+
+	if err := sortDebts(debts, strategy, order); err != nil {
+		return plan, err
+	}
+
+	for month := 1; ; month++ {
+		if month > maxAmortizationMonths {
+			return plan, ErrScheduleTooLong
+		}
+
+		row := models.DebtPlanMonth{Month: month, Date: AddMonths(first, month-1).Format("2006-01-02")}
+
+		// Accrue interest, then pay minimums
+		available := budget
+		accrued := 0.0
+		payments := make(map[*debt]float64, len(debts))
+		for _, d := range debts {
+			if d.paidOff > 0 {
+				continue
+			}
+			interest := RoundCents(d.balance * d.node.InterestRate / 100 / 12)
+			d.balance += interest
+			d.interest += interest
+			accrued += interest
+
+			pay := math.Min(d.minimum, d.balance)
+			payments[d] = pay
+			available -= pay
+		}
+		if month == 1 && accrued >= budget {
+			return plan, ErrNeverPaysOff
+		}
+
+		// Everything left over goes to debts in strategy order
+		for _, d := range debts {
+			if d.paidOff > 0 || available <= 0 {
+				continue
+			}
+			pay := math.Min(available, d.balance-payments[d])
+			payments[d] += pay
+			available -= pay
+		}
+
+		for _, d := range debts {
+			pay, ok := payments[d]
+			if !ok {
+				continue
+			}
+			d.balance = RoundCents(d.balance - pay)
+			if d.balance <= 0.005 {
+				d.balance = 0
+				d.paidOff = month
+				plan.Payoffs = append(plan.Payoffs, models.DebtPayoff{
+					NodeID:       d.node.ID,
+					Label:        d.node.Label,
+					Month:        month,
+					PayoffDate:   row.Date,
+					InterestPaid: RoundCents(d.interest),
+				})
+			}
+			row.Payments = append(row.Payments, models.DebtPlanPayment{
+				NodeID:  d.node.ID,
+				Payment: RoundCents(pay),
+				Balance: d.balance,
+			})
+			row.TotalPaid += pay
+			row.Remaining += d.balance
+		}
+		row.TotalPaid = RoundCents(row.TotalPaid)
+		row.Remaining = RoundCents(row.Remaining)
+
+		plan.Schedule = append(plan.Schedule, row)
+		plan.TotalPaid += row.TotalPaid
+		plan.TotalInterest += accrued
+
+		if row.Remaining == 0 {
+			plan.Months = month
+			plan.DebtFreeDate = row.Date
+			break
+		}
+	}
+
+	plan.TotalPaid = RoundCents(plan.TotalPaid)
+	plan.TotalInterest = RoundCents(plan.TotalInterest)
+	return plan, nil
+}
+
+func sortDebts(debts []*debt, strategy string, order []int64) error {
+	snowball := func(i, j int) bool {
+		if debts[i].balance != debts[j].balance {
+			return debts[i].balance < debts[j].balance
+		}
+		return debts[i].node.ID < debts[j].node.ID
+	}
+
+	switch strategy {
+	case StrategySnowball:
+		sort.SliceStable(debts, snowball)
+	case StrategyAvalanche:
+		sort.SliceStable(debts, func(i, j int) bool {
+			if debts[i].node.InterestRate != debts[j].node.InterestRate {
+				return debts[i].node.InterestRate > debts[j].node.InterestRate
+			}
+			return snowball(i, j)
+		})
+	case StrategyCustom:
+		rank := make(map[int64]int, len(order))
+		for i, id := range order {
+			rank[id] = i
+		}
+		for _, id := range order {
+			found := false
+			for _, d := range debts {
+				found = found || d.node.ID == id
+			}
+			if !found {
+				return fmt.Errorf("node %d is not an outstanding liability", id)
+			}
+		}
+		sort.SliceStable(debts, func(i, j int) bool {
+			ri, iok := rank[debts[i].node.ID]
+			rj, jok := rank[debts[j].node.ID]
+			switch {
+			case iok && jok:
+				return ri < rj
+			case iok != jok:
+				return iok
+			}
+			return snowball(i, j)
+		})
+	default:
+		return fmt.Errorf("unknown strategy %q", strategy)
+	}
+
+	return nil
+}
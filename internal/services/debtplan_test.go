@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+func TestDebtBudget(t *testing.T) {
+	data := &ProfileData{
+		Nodes: []models.Node{
+			{ID: 1, Type: "account", Balance: 5000},
+			{ID: 2, Type: "credit_card", Balance: 1200, MinimumPayment: 35},
+			{ID: 3, Type: "loan", Balance: 8000, MinimumPayment: 250},
+			{ID: 4, Type: "credit_card", Balance: 0, MinimumPayment: 25},     // Paid off
+			{ID: 5, Type: "liability", Balance: 0, MinimumPayment: 100},      // Paid off, leftover flow
+			{ID: 6, Type: "credit_card", Balance: -15.5, MinimumPayment: 25}, // Overpaid
+		},
+		Flows: []models.Flow{
+			{FromNodeID: 1, ToNodeID: 3, Amount: 300},
+			{FromNodeID: 1, ToNodeID: 5, Amount: 100},
+		},
+	}
+
+	// The card's minimum and the loan's flow; nothing for paid-off debts
+	if got := DebtBudget(data); got != 335 {
+		t.Errorf("DebtBudget = %v, want 335", got)
+	}
+}
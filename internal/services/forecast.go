@@ -0,0 +1,110 @@
+package services
+
+import (
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// ExpenseAmounts normalises an expense to monthly and annual amounts
+func ExpenseAmounts(e models.Expense) (monthly, annual float64) {
+	switch e.Period {
+	case "weekly":
+		annual = e.Amount * 52
+	case "quarterly":
+		annual = e.Amount * 4
+	case "annual":
+		annual = e.Amount
+	default:
+		annual = e.Amount * 12
+	}
+	return annual / 12, annual
+}
+
+// BuildForecast projects fixed expenses and APY-bearing balances over the
+// months following now. Quarterly and annual expenses with a due date land
+// as spikes in the months they fall due; without one, or with a sinking
+// fund setting them aside, they are spread evenly.
+func BuildForecast(data *ProfileData, now time.Time, months int) models.ForecastResponse {
+	resp := models.ForecastResponse{
+		MonthlyProjection:  []models.MonthProjection{},
+		AnnualExpenses:     []models.Expense{},
+		BalanceProjections: []models.BalanceProjection{},
+	}
+
+	start := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	projection := make([]models.MonthProjection, months)
+	for i := range projection {
+		projection[i].Month = start.AddDate(0, i, 0).Format("2006-01")
+	}
+
+	sinking := map[int64]bool{}
+	for _, f := range data.SinkingFunds {
+		sinking[f.ExpenseID] = true
+	}
+
+	for _, e := range data.Expenses {
+		monthly, annual := ExpenseAmounts(e)
+		resp.AnnualTotal += annual
+
+		if e.Period == "annual" {
+			e.MonthlyAmount, e.AnnualAmount = RoundCents(monthly), RoundCents(annual)
+			resp.AnnualExpenses = append(resp.AnnualExpenses, e)
+		}
+
+		due, err := time.Parse("2006-01-02", e.NextDue)
+		spiky := (e.Period == "quarterly" || e.Period == "annual") && err == nil && !sinking[e.ID]
+		for i := range projection {
+			if !spiky {
+				projection[i].Baseline += monthly
+				continue
+			}
+			month := start.AddDate(0, i, 0)
+			offset := (month.Year()-due.Year())*12 + int(month.Month()-due.Month())
+			if offset >= 0 && (e.Period == "quarterly" && offset%3 == 0 || e.Period == "annual" && offset%12 == 0) {
+				projection[i].Spikes += e.Amount
+			}
+		}
+	}
+
+	for i := range projection {
+		projection[i].Baseline = RoundCents(projection[i].Baseline)
+		projection[i].Spikes = RoundCents(projection[i].Spikes)
+		projection[i].Total = RoundCents(projection[i].Baseline + projection[i].Spikes)
+	}
+	resp.MonthlyProjection = projection
+	resp.AnnualTotal = RoundCents(resp.AnnualTotal)
+	resp.MonthlyAverage = RoundCents(resp.AnnualTotal / 12)
+
+	net := map[int64]float64{}
+	for _, f := range data.Flows {
+		net[f.ToNodeID] += f.Amount
+		net[f.FromNodeID] -= f.Amount
+	}
+
+	for _, n := range data.Nodes {
+		if !InterestBearing(n.Type) {
+			continue
+		}
+		p := models.BalanceProjection{NodeID: n.ID, Label: n.Label, APY: n.APY, Current: n.Balance, Months: []models.BalancePoint{}}
+		rate := MonthlyRate(n.APY)
+		balance := n.Balance
+		for i := 0; i < months; i++ {
+			interest := 0.0
+			if balance > 0 {
+				interest = balance * rate
+			}
+			balance += interest + net[n.ID]
+			p.Interest += interest
+			p.Months = append(p.Months, models.BalancePoint{
+				Month:    start.AddDate(0, i, 0).Format("2006-01"),
+				Balance:  RoundCents(balance),
+				Interest: RoundCents(interest),
+			})
+		}
+		p.Interest = RoundCents(p.Interest)
+		resp.BalanceProjections = append(resp.BalanceProjections, p)
+	}
+
+	return resp
+}
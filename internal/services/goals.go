@@ -0,0 +1,195 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Goal statuses
+const (
+	GoalComplete = "complete"
+	GoalOnTrack  = "on_track"
+	GoalBehind   = "behind"
+	GoalOverdue  = "overdue"
+	GoalStalled  = "stalled" // No deadline and nothing going in
+	GoalArchived = "archived"
+)
+
+const (
+	// goalTrailingMonths is the window the contribution rate is averaged over
+	goalTrailingMonths = 3
+
+	// goalProjectionLimit caps the search for a completion date
+	goalProjectionLimit = 600
+
+	daysPerMonth = 365.25 / 12
+)
+
+// AssessGoals fills in each goal's progress and feasibility: the trailing
+// contribution rate, the planned rate from flows into its node, the
+// projected completion date at the rate it is actually funded, whether
+// that meets the deadline, and a share of the monthly surplus handed out in
+// priority order. Goals are returned by priority, then deadline, with
+// open-ended goals last; archived goals are included but get no surplus.
+//
+// The projection uses the contribution history when there is any in the
+// trailing window, since that is what is really being saved, and falls back
+// to the planned flows otherwise.
+func AssessGoals(data *ProfileData, now time.Time) []models.Goal {
+	income, expenses, debt := monthlyCashFlow(data)
+	return assessGoals(data, now, income-expenses-debt)
+}
+
+func assessGoals(data *ProfileData, now time.Time, surplus float64) []models.Goal {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	apy := map[int64]float64{}
+	for _, n := range data.Nodes {
+		apy[n.ID] = n.APY
+	}
+	planned := map[int64]float64{}
+	for _, f := range data.Flows {
+		planned[f.ToNodeID] += f.Amount
+		planned[f.FromNodeID] -= f.Amount
+	}
+
+	windowStart := today.AddDate(0, -goalTrailingMonths, 0)
+	contributed := map[int64]float64{}
+	for _, t := range data.GoalTransactions {
+		// Opening balances, adjustments, rollovers and repairs aren't saving
+		if t.Kind != GoalTxContribution {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", t.Date)
+		if err == nil && date.After(windowStart) && !date.After(today) {
+			contributed[t.GoalID] += t.Amount
+		}
+	}
+
+	goals := make([]models.Goal, 0, len(data.Goals))
+	for _, g := range data.Goals {
+		if g.NodeID != 0 {
+			g.APY = apy[g.NodeID]
+			g.PlannedRate = RoundCents(planned[g.NodeID])
+		}
+		if g.Target > 0 {
+			g.Percentage = (g.Current / g.Target) * 100
+		}
+
+		// A goal younger than the window is averaged over its own life
+		start := windowStart
+		if created := g.CreatedAt.UTC(); created.After(start) {
+			start = created
+		}
+		months := math.Max(today.Sub(start).Hours()/24/daysPerMonth, 1)
+		g.ContributionRate = RoundCents(contributed[g.ID] / months)
+
+		g.ProjectedRate = g.PlannedRate
+		if contributed[g.ID] != 0 {
+			g.ProjectedRate = g.ContributionRate
+		}
+
+		var deadline time.Time
+		if g.Deadline != "" {
+			if d, err := time.Parse("2006-01-02", g.Deadline); err == nil {
+				deadline = d
+				g.DaysRemaining = int(d.Sub(now).Hours() / 24)
+				if g.DaysRemaining > 0 {
+					g.MonthlyNeeded = RoundCents(MonthlyContributionNeeded(g.Current, g.Target, g.APY, float64(g.DaysRemaining)/daysPerMonth))
+				}
+			}
+		}
+
+		var completion time.Time
+		if g.Current < g.Target {
+			if m := monthsToTarget(g.Current, g.Target, g.APY, g.ProjectedRate); m >= 0 {
+				completion = today.AddDate(0, m, 0)
+				g.ProjectedCompletion = completion.Format("2006-01-02")
+			}
+		}
+
+		switch {
+		case g.Current >= g.Target:
+			g.Status = GoalComplete
+		case !deadline.IsZero() && deadline.Before(today):
+			g.Status = GoalOverdue
+		case !deadline.IsZero():
+			g.Status = GoalOnTrack
+			if completion.IsZero() || completion.After(deadline) {
+				g.Status = GoalBehind
+			}
+		case completion.IsZero():
+			g.Status = GoalStalled
+		default:
+			g.Status = GoalOnTrack
+		}
+		if g.ArchivedAt != nil {
+			g.Status = GoalArchived
+		}
+
+		goals = append(goals, g)
+	}
+
+	sort.SliceStable(goals, func(i, j int) bool {
+		if goals[i].Priority != goals[j].Priority {
+			return goals[i].Priority < goals[j].Priority
+		}
+		if (goals[i].Deadline == "") != (goals[j].Deadline == "") {
+			return goals[j].Deadline == ""
+		}
+		return goals[i].Deadline < goals[j].Deadline
+	})
+	allocateSurplus(goals, surplus)
+
+	return goals
+}
+
+// ActiveGoals drops archived goals
+func ActiveGoals(goals []models.Goal) []models.Goal {
+	active := make([]models.Goal, 0, len(goals))
+	for _, g := range goals {
+		if g.ArchivedAt == nil {
+			active = append(active, g)
+		}
+	}
+	return active
+}
+
+// monthsToTarget is the number of whole months for current to reach target
+// with monthly deposits and interest, or -1 if it never does
+func monthsToTarget(current, target, apy, monthly float64) int {
+	r := MonthlyRate(apy)
+	balance := current
+	for m := 1; m <= goalProjectionLimit; m++ {
+		if balance > 0 {
+			balance += balance * r
+		}
+		balance += monthly
+		if balance >= target {
+			return m
+		}
+	}
+	return -1
+}
+
+// allocateSurplus hands the monthly surplus to goals in order, each getting
+// what it needs to meet its deadline. Goals without a deadline, or past it,
+// may take all they are short.
+func allocateSurplus(goals []models.Goal, surplus float64) {
+	for i := range goals {
+		g := &goals[i]
+		if surplus <= 0 || g.Status == GoalComplete || g.Status == GoalArchived {
+			continue
+		}
+
+		need := g.MonthlyNeeded
+		if g.DaysRemaining <= 0 {
+			need = g.Target - g.Current
+		}
+		g.SuggestedMonthly = RoundCents(math.Min(need, surplus))
+		surplus -= g.SuggestedMonthly
+	}
+}
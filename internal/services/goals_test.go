@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+func TestAssessGoalsContributionRate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	data := &ProfileData{
+		Goals: []models.Goal{
+			{ID: 1, Name: "Emergency fund", Target: 10000, Current: 2310, CreatedAt: now.AddDate(-1, 0, 0)},
+		},
+		GoalTransactions: []models.GoalTransaction{
+			{GoalID: 1, Amount: 1000, Date: "2025-10-18", Kind: GoalTxOpening},
+			{GoalID: 1, Amount: 400, Date: "2026-05-01", Kind: GoalTxContribution}, // Before the window
+			{GoalID: 1, Amount: 300, Date: "2026-08-01", Kind: GoalTxContribution},
+			{GoalID: 1, Amount: 300, Date: "2026-09-01", Kind: GoalTxContribution, FlowID: 7},
+			{GoalID: 1, Amount: 500, Date: "2026-09-15", Kind: GoalTxAdjustment},
+			{GoalID: 1, Amount: 200, Date: "2026-10-01", Kind: GoalTxRollover},
+			{GoalID: 1, Amount: -390, Date: "2026-10-02", Kind: GoalTxReconcile},
+		},
+	}
+
+	goals := assessGoals(data, now, 0)
+	if len(goals) != 1 {
+		t.Fatalf("got %d goals, want 1", len(goals))
+	}
+	// Only the two contributions in the last 3 months (92 days) count
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	want := RoundCents(600 / (today.Sub(today.AddDate(0, -goalTrailingMonths, 0)).Hours() / 24 / daysPerMonth))
+	if g := goals[0]; g.ContributionRate != want || g.ProjectedRate != want {
+		t.Errorf("contribution rate %v, projected %v; want %v", g.ContributionRate, g.ProjectedRate, want)
+	}
+
+	// A goal funded only by its opening balance has nothing going in
+	data.GoalTransactions = data.GoalTransactions[:1]
+	if g := assessGoals(data, now, 0)[0]; g.ContributionRate != 0 || g.Status != GoalStalled {
+		t.Errorf("contribution rate %v, status %q; want 0, %q", g.ContributionRate, g.Status, GoalStalled)
+	}
+}
+
+func TestClassifyGoalTransactions(t *testing.T) {
+	s := newTestStore(t)
+	mustExec := func(query string, args ...any) int64 {
+		t.Helper()
+		result, err := s.db.Exec(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		return id
+	}
+	mustExec("INSERT INTO users (email, password_hash) VALUES ('a@b.c', 'x')")
+	profileID := mustExec("INSERT INTO profiles (user_id, name) VALUES (1, 'Me')")
+	goalID := mustExec("INSERT INTO goals (profile_id, name, target) VALUES (?, 'Car', 5000)", profileID)
+	nodeID := mustExec("INSERT INTO nodes (profile_id, type, label) VALUES (?, 'income', 'Salary')", profileID)
+	flowID := mustExec("INSERT INTO flows (profile_id, from_node_id, to_node_id, amount) VALUES (?, ?, ?, 100)", profileID, nodeID, nodeID)
+
+	tests := []struct {
+		note   string
+		flowID any
+		kind   string
+	}{
+		{note: "Opening balance", kind: GoalTxOpening},
+		{note: "Adjustment", kind: GoalTxAdjustment},
+		{note: "Rolled over from House", kind: GoalTxRollover},
+		{note: "Rolled over to House", kind: GoalTxRollover},
+		{note: "Reconciliation", kind: GoalTxReconcile},
+		{note: "Birthday money", kind: GoalTxContribution},
+		{note: "", kind: GoalTxContribution},
+		{note: "Adjustment", flowID: flowID, kind: GoalTxContribution},
+	}
+	for i, tt := range tests {
+		mustExec("INSERT INTO goal_transactions (goal_id, amount, note, date, flow_id) VALUES (?, 10, ?, ?, ?)",
+			goalID, tt.note, time.Date(2026, 1, i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), tt.flowID)
+	}
+	// Rows that already have a kind are left alone
+	mustExec("INSERT INTO goal_transactions (goal_id, amount, note, date, kind) VALUES (?, 10, 'Opening balance', '2026-02-01', ?)", goalID, GoalTxContribution)
+
+	n, err := s.ClassifyGoalTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(tests) {
+		t.Errorf("classified %d, want %d", n, len(tests))
+	}
+
+	txs, err := s.GoalTransactions(profileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != len(tests)+1 {
+		t.Fatalf("got %d transactions, want %d", len(txs), len(tests)+1)
+	}
+	for i, tt := range tests {
+		if txs[i].Kind != tt.kind {
+			t.Errorf("%q: kind = %q, want %q", tt.note, txs[i].Kind, tt.kind)
+		}
+	}
+	if kind := txs[len(tests)].Kind; kind != GoalTxContribution {
+		t.Errorf("already classified: kind = %q, want %q", kind, GoalTxContribution)
+	}
+
+	if n, err := s.ClassifyGoalTransactions(); err != nil || n != 0 {
+		t.Errorf("second run classified %d (%v), want 0", n, err)
+	}
+}
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// BalanceReconciled is the balance history source for repairs made by
+// ReconcileGoals
+const BalanceReconciled = "reconcile"
+
+// UpdateGoal changes a goal and its node together: the node's label and
+// goal amount follow the goal's name and target, a changed current amount
+// is recorded as an adjustment so the balance and transactions follow too,
+// and the goal is settled against its new target. Returns sql.ErrNoRows if the profile doesn't own the goal.
+func (s *Store) UpdateGoal(profileID, goalID int64, req models.CreateGoalRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.updateGoal(tx, profileID, goalID, req); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) updateGoal(tx *sql.Tx, profileID, goalID int64, req models.CreateGoalRequest) error {
+	name := req.Name
+	if err := s.crypt.EncryptAll(&name); err != nil {
+		return err
+	}
+
+	var current float64
+	var nodeID sql.NullInt64
+	err := tx.QueryRow("SELECT current, node_id FROM goals WHERE id = ? AND profile_id = ?", goalID, profileID).Scan(&current, &nodeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE goals SET name = ?, target = ?, deadline = ?, priority = ?, color = ?, rollover = ?
+		WHERE id = ?
+	`, name, req.Target, req.Deadline, req.Priority, req.Color, req.Rollover, goalID)
+	if err != nil {
+		return err
+	}
+
+	if nodeID.Valid {
+		_, err := tx.Exec("UPDATE nodes SET label = ?, goal = ? WHERE id = ? AND profile_id = ?", name, req.Target, nodeID.Int64, profileID)
+		if err != nil {
+			return err
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if diff := RoundCents(req.Current - current); diff != 0 {
+		_, err := s.ApplyGoalContribution(tx, models.GoalTransaction{
+			GoalID: goalID,
+			Amount: diff,
+			Note:   "Adjustment",
+			Date:   today,
+			Kind:   GoalTxAdjustment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// A new target can pass or reopen milestones without any money moving
+	return s.settleGoal(tx, goalID, today)
+}
+
+// UpdateGoalNode edits a goal's node through its goal, so the node can't
+// drift from it: the label, goal amount and balance become the goal's name,
+// target and current amount as in UpdateGoal, and the node's other fields
+// are written directly. Returns sql.ErrNoRows if no goal in the profile
+// uses the node.
+func (s *Store) UpdateGoalNode(profileID, nodeID int64, req models.CreateNodeRequest) error {
+	institution := req.Institution
+	if err := s.crypt.EncryptAll(&institution); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var goalID int64
+	var deadline sql.NullString
+	goal := models.CreateGoalRequest{}
+	err = tx.QueryRow(`
+		SELECT id, name, deadline, priority, color, COALESCE(rollover, 0)
+		FROM goals WHERE node_id = ? AND profile_id = ?
+	`, nodeID, profileID).Scan(&goalID, &goal.Name, &deadline, &goal.Priority, &goal.Color, &goal.Rollover)
+	if err != nil {
+		return err
+	}
+	if err := s.crypt.DecryptAll(&goal.Name); err != nil {
+		return err
+	}
+	if req.Label != "" {
+		goal.Name = req.Label
+	}
+	goal.Deadline = DateOnly(deadline.String)
+	goal.Target, goal.Current = req.Goal, req.Balance
+
+	_, err = tx.Exec(`
+		UPDATE nodes SET
+			institution = ?,
+			amount = ?,
+			apy = ?,
+			budgeted = ?,
+			metadata = COALESCE(NULLIF(?, ''), metadata),
+			principal = ?,
+			interest_rate = ?,
+			term_months = ?,
+			minimum_payment = ?
+		WHERE id = ? AND profile_id = ?
+	`, institution, req.Amount, req.APY, req.Budgeted, req.Metadata,
+		req.Principal, req.InterestRate, req.TermMonths, req.MinimumPayment, nodeID, profileID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.updateGoal(tx, profileID, goalID, goal); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteGoal removes a goal with its transactions and milestones, its node and the flows
+// into and out of that node. Returns sql.ErrNoRows if the profile doesn't
+// own the goal.
+func (s *Store) DeleteGoal(profileID, goalID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var nodeID sql.NullInt64
+	err = tx.QueryRow("SELECT node_id FROM goals WHERE id = ? AND profile_id = ?", goalID, profileID).Scan(&nodeID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM goal_transactions WHERE goal_id = ?", goalID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM goal_milestones WHERE goal_id = ?", goalID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM goals WHERE id = ?", goalID); err != nil {
+		return err
+	}
+	if nodeID.Valid {
+		if err := deleteGoalNode(tx, profileID, nodeID.Int64); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GoalForNode returns the goal linked to a node, or sql.ErrNoRows
+func (s *Store) GoalForNode(profileID, nodeID int64) (int64, error) {
+	var goalID int64
+	err := s.db.QueryRow("SELECT id FROM goals WHERE node_id = ? AND profile_id = ?", nodeID, profileID).Scan(&goalID)
+	return goalID, err
+}
+
+func deleteGoalNode(tx *sql.Tx, profileID, nodeID int64) error {
+	if _, err := tx.Exec("DELETE FROM flows WHERE (from_node_id = ? OR to_node_id = ?) AND profile_id = ?", nodeID, nodeID, profileID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM nodes WHERE id = ? AND profile_id = ? AND type = 'goal'", nodeID, profileID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+	return deleteNodeDependents(tx, nodeID)
+}
+
+// DeleteNode deletes a node with its flows, sinking fund, interest
+// accruals, balance history and holdings in one transaction; a goal's node
+// goes with the goal. Budgets on the node are kept, unlinked, so their
+// spending isn't lost. Returns sql.ErrNoRows if the profile has no such node.
+func (s *Store) DeleteNode(profileID, nodeID int64) error {
+	goalID, err := s.GoalForNode(profileID, nodeID)
+	if err == nil {
+		return s.DeleteGoal(profileID, goalID)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM nodes WHERE id = ? AND profile_id = ?", nodeID, profileID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec("DELETE FROM flows WHERE (from_node_id = ? OR to_node_id = ?) AND profile_id = ?", nodeID, nodeID, profileID); err != nil {
+		return err
+	}
+	if err := deleteNodeDependents(tx, nodeID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteNodeDependents removes what refers to a deleted node. Foreign keys
+// aren't enforced, so the schema's ON DELETE clauses never run.
+func deleteNodeDependents(tx *sql.Tx, nodeID int64) error {
+	for _, query := range []string{
+		"DELETE FROM sinking_funds WHERE node_id = ?",
+		"DELETE FROM interest_accruals WHERE node_id = ?",
+		"DELETE FROM node_balance_history WHERE node_id = ?",
+		"DELETE FROM holding_transactions WHERE holding_id IN (SELECT id FROM holdings WHERE node_id = ?)",
+		"DELETE FROM holdings WHERE node_id = ?",
+		"UPDATE budgets SET node_id = NULL WHERE node_id = ?",
+	} {
+		if _, err := tx.Exec(query, nodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GoalDrift is one disagreement between a goal and its node found by
+// ReconcileGoals
+type GoalDrift struct {
+	GoalID int64  `json:"goal_id,omitempty"`
+	NodeID int64  `json:"node_id,omitempty"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// Kinds of goal drift
+const (
+	DriftMissingNode        = "missing_node"        // Goal has no goal node; one is created
+	DriftLabel              = "label"               // Node label differs from the goal name
+	DriftTarget             = "target"              // Node goal amount differs from the target
+	DriftBalance            = "balance"             // Node balance differs from current
+	DriftTransactions       = "transactions"        // Transactions don't sum to current
+	DriftOrphanTransactions = "orphan_transactions" // Transactions of a deleted goal
+	DriftOrphanNode         = "orphan_node"         // Goal node no goal links to
+)
+
+// ReconcileOptions control what ReconcileGoals changes
+type ReconcileOptions struct {
+	Repair        bool // Without it the drift is only reported
+	DeleteOrphans bool // Also delete orphan goal nodes and their flows
+}
+
+// ReconcileGoals finds drift between goals, their nodes and their
+// transactions across all profiles, and repairs it when asked. The goal's
+// current amount is taken as the truth: the node balance is set to it and
+// a "Reconciliation" transaction makes up any difference in the sum.
+// Goal nodes without a goal are only deleted with DeleteOrphans, since
+// they can also be created directly as nodes.
+func (s *Store) ReconcileGoals(opts ReconcileOptions) ([]GoalDrift, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	type goalRow struct {
+		id, profileID         int64
+		name                  string // As stored, possibly encrypted
+		target, current, sum  float64
+		nodeID                sql.NullInt64
+		nodeType, label       sql.NullString
+		nodeGoal, nodeBalance sql.NullFloat64
+	}
+
+	rows, err := tx.Query(`
+		SELECT g.id, g.profile_id, g.name, g.target, g.current,
+			(SELECT COALESCE(SUM(amount), 0) FROM goal_transactions WHERE goal_id = g.id),
+			n.id, n.type, n.label, n.goal, n.balance
+		FROM goals g
+		LEFT JOIN nodes n ON n.id = g.node_id AND n.profile_id = g.profile_id
+		ORDER BY g.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var goals []goalRow
+	for rows.Next() {
+		var g goalRow
+		if err := rows.Scan(&g.id, &g.profileID, &g.name, &g.target, &g.current, &g.sum,
+			&g.nodeID, &g.nodeType, &g.label, &g.nodeGoal, &g.nodeBalance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	drift := []GoalDrift{}
+	today := time.Now().Format("2006-01-02")
+	for _, g := range goals {
+		name := g.name
+		if err := s.crypt.DecryptAll(&name); err != nil {
+			return nil, fmt.Errorf("goal %d: %w", g.id, err)
+		}
+
+		if !g.nodeID.Valid || g.nodeType.String != "goal" {
+			drift = append(drift, GoalDrift{GoalID: g.id, Kind: DriftMissingNode, Detail: fmt.Sprintf("goal %q has no goal node", name)})
+			if opts.Repair {
+				result, err := tx.Exec(`
+					INSERT INTO nodes (profile_id, type, label, balance, goal)
+					VALUES (?, 'goal', ?, ?, ?)
+				`, g.profileID, g.name, g.current, g.target)
+				if err != nil {
+					return nil, err
+				}
+				nodeID, _ := result.LastInsertId()
+				if _, err := tx.Exec("UPDATE goals SET node_id = ? WHERE id = ?", nodeID, g.id); err != nil {
+					return nil, err
+				}
+				if err := RecordBalance(tx, nodeID, g.current, BalanceReconciled); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			nodeID := g.nodeID.Int64
+			label := g.label.String
+			if err := s.crypt.DecryptAll(&label); err != nil {
+				return nil, fmt.Errorf("node %d: %w", nodeID, err)
+			}
+
+			if label != name {
+				drift = append(drift, GoalDrift{GoalID: g.id, NodeID: nodeID, Kind: DriftLabel, Detail: fmt.Sprintf("node %q, goal %q", label, name)})
+				if opts.Repair {
+					if _, err := tx.Exec("UPDATE nodes SET label = ? WHERE id = ?", g.name, nodeID); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if RoundCents(g.nodeGoal.Float64) != RoundCents(g.target) {
+				drift = append(drift, GoalDrift{GoalID: g.id, NodeID: nodeID, Kind: DriftTarget, Detail: fmt.Sprintf("node %.2f, goal %.2f", g.nodeGoal.Float64, g.target)})
+				if opts.Repair {
+					if _, err := tx.Exec("UPDATE nodes SET goal = ? WHERE id = ?", g.target, nodeID); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if RoundCents(g.nodeBalance.Float64) != RoundCents(g.current) {
+				drift = append(drift, GoalDrift{GoalID: g.id, NodeID: nodeID, Kind: DriftBalance, Detail: fmt.Sprintf("node %.2f, goal %.2f", g.nodeBalance.Float64, g.current)})
+				if opts.Repair {
+					if _, err := tx.Exec("UPDATE nodes SET balance = ? WHERE id = ?", g.current, nodeID); err != nil {
+						return nil, err
+					}
+					if err := RecordBalance(tx, nodeID, g.current, BalanceReconciled); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		if diff := RoundCents(g.current - g.sum); diff != 0 {
+			drift = append(drift, GoalDrift{GoalID: g.id, Kind: DriftTransactions, Detail: fmt.Sprintf("transactions %.2f, goal %.2f", g.sum, g.current)})
+			if opts.Repair {
+				note := "Reconciliation"
+				if err := s.crypt.EncryptAll(&note); err != nil {
+					return nil, err
+				}
+				_, err := tx.Exec("INSERT INTO goal_transactions (goal_id, amount, note, date, kind) VALUES (?, ?, ?, ?, ?)", g.id, diff, note, today, GoalTxReconcile)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var orphanTxs int64
+	if err := tx.QueryRow("SELECT COUNT(*) FROM goal_transactions WHERE goal_id NOT IN (SELECT id FROM goals)").Scan(&orphanTxs); err != nil {
+		return nil, err
+	}
+	if orphanTxs > 0 {
+		drift = append(drift, GoalDrift{Kind: DriftOrphanTransactions, Detail: fmt.Sprintf("%d transactions of deleted goals", orphanTxs)})
+		if opts.Repair {
+			if _, err := tx.Exec("DELETE FROM goal_transactions WHERE goal_id NOT IN (SELECT id FROM goals)"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rows, err = tx.Query(`
+		SELECT id, profile_id, label FROM nodes
+		WHERE type = 'goal' AND id NOT IN (SELECT node_id FROM goals WHERE node_id IS NOT NULL)
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	type orphan struct {
+		id, profileID int64
+		label         string
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var o orphan
+		if err := rows.Scan(&o.id, &o.profileID, &o.label); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		orphans = append(orphans, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, o := range orphans {
+		if err := s.crypt.DecryptAll(&o.label); err != nil {
+			return nil, fmt.Errorf("node %d: %w", o.id, err)
+		}
+		drift = append(drift, GoalDrift{NodeID: o.id, Kind: DriftOrphanNode, Detail: fmt.Sprintf("goal node %q has no goal", o.label)})
+		if opts.Repair && opts.DeleteOrphans {
+			if err := deleteGoalNode(tx, o.profileID, o.id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !opts.Repair {
+		return drift, nil
+	}
+	return drift, tx.Commit()
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestDeleteNode(t *testing.T) {
+	s := newTestStore(t)
+	mustExec := func(query string, args ...any) int64 {
+		t.Helper()
+		result, err := s.db.Exec(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		return id
+	}
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := s.db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	mustExec("INSERT INTO users (email, password_hash) VALUES ('a@b.c', 'x')")
+	profileID := mustExec("INSERT INTO profiles (user_id, name) VALUES (1, 'Me')")
+	otherID := mustExec("INSERT INTO profiles (user_id, name) VALUES (1, 'Other')")
+	checking := mustExec("INSERT INTO nodes (profile_id, type, label, balance) VALUES (?, 'account', 'Checking', 100)", profileID)
+	savings := mustExec("INSERT INTO nodes (profile_id, type, label, balance, apy) VALUES (?, 'savings', 'Savings', 1000, 4)", profileID)
+	mustExec("INSERT INTO flows (profile_id, from_node_id, to_node_id, amount) VALUES (?, ?, ?, 50)", profileID, checking, savings)
+	expense := mustExec("INSERT INTO expenses (profile_id, name, amount, period) VALUES (?, 'Insurance', 600, 'annual')", profileID)
+	mustExec("INSERT INTO sinking_funds (profile_id, expense_id, node_id) VALUES (?, ?, ?)", profileID, expense, savings)
+	mustExec("INSERT INTO interest_accruals (node_id, amount, balance_after, apy, period_start, period_end) VALUES (?, 3.33, 1003.33, 4, '2026-09-01', '2026-09-30')", savings)
+	mustExec("INSERT INTO node_balance_history (node_id, balance, source) VALUES (?, 1000, 'create')", savings)
+	holding := mustExec("INSERT INTO holdings (node_id, symbol) VALUES (?, 'VTI')", savings)
+	mustExec("INSERT INTO holding_transactions (holding_id, type, date, quantity, price, amount) VALUES (?, 'buy', '2026-01-02', 1, 200, 200)", holding)
+	budget := mustExec("INSERT INTO budgets (profile_id, node_id, name, budgeted) VALUES (?, ?, 'Savings', 100)", profileID, savings)
+	mustExec("INSERT INTO transactions (budget_id, amount, date) VALUES (?, 10, '2026-10-02')", budget)
+
+	if err := s.DeleteNode(otherID, savings); err != sql.ErrNoRows {
+		t.Fatalf("deleting another profile's node: err = %v, want sql.ErrNoRows", err)
+	}
+	if err := s.DeleteNode(profileID, savings); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{
+		"SELECT COUNT(*) FROM nodes WHERE id = ?",
+		"SELECT COUNT(*) FROM flows WHERE from_node_id = ? OR to_node_id = ?1",
+		"SELECT COUNT(*) FROM sinking_funds WHERE node_id = ?",
+		"SELECT COUNT(*) FROM interest_accruals WHERE node_id = ?",
+		"SELECT COUNT(*) FROM node_balance_history WHERE node_id = ?",
+		"SELECT COUNT(*) FROM holdings WHERE node_id = ?",
+		"SELECT COUNT(*) FROM budgets WHERE node_id = ?",
+	} {
+		if n := count(q, savings); n != 0 {
+			t.Errorf("%s: %d left", q, n)
+		}
+	}
+	if n := count("SELECT COUNT(*) FROM holding_transactions WHERE holding_id = ?", holding); n != 0 {
+		t.Errorf("%d holding transactions left", n)
+	}
+	// The budget and its spending stay, just unlinked
+	if n := count("SELECT COUNT(*) FROM transactions WHERE budget_id = ?", budget); n != 1 {
+		t.Errorf("budget transactions = %d, want 1", n)
+	}
+	if n := count("SELECT COUNT(*) FROM nodes WHERE id = ?", checking); n != 1 {
+		t.Errorf("other nodes deleted")
+	}
+
+	if err := s.DeleteNode(profileID, savings); err != sql.ErrNoRows {
+		t.Errorf("deleting twice: err = %v, want sql.ErrNoRows", err)
+	}
+}
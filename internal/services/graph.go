@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+var (
+	ErrSelfLoop         = errors.New("a flow cannot start and end at the same node")
+	ErrFlowNodeNotFound = errors.New("flow endpoints must be nodes in this profile")
+)
+
+// Flow issue severities; errors make a graph invalid
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// CheckFlowEndpoints rejects flows that loop back to their source or touch
+// a node outside the profile
+func (s *Store) CheckFlowEndpoints(profileID, fromID, toID int64) error {
+	if fromID == toID {
+		return ErrSelfLoop
+	}
+
+	var count int
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM nodes WHERE id IN (?, ?) AND profile_id = ?",
+		fromID, toID, profileID,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 2 {
+		return ErrFlowNodeNotFound
+	}
+	return nil
+}
+
+// ValidateFlows checks a profile's flow graph for conservation of money and
+// cycles. Income nodes supply their amount each month; spending more than
+// that is an error, spending less leaves income unallocated. Any other node
+// sending more than it receives is drawing down its balance, which is only
+// a warning, as are cycles.
+func ValidateFlows(data *ProfileData) models.FlowValidationReport {
+	report := models.FlowValidationReport{
+		Valid:  true,
+		Issues: []models.FlowIssue{},
+		Cycles: [][]int64{},
+		Nodes:  []models.NodeFlowBalance{},
+	}
+	issue := func(severity, code, message string, nodeID, flowID int64) {
+		report.Issues = append(report.Issues, models.FlowIssue{Severity: severity, Code: code, Message: message, NodeID: nodeID, FlowID: flowID})
+		if severity == IssueError {
+			report.Valid = false
+		}
+	}
+
+	nodes := make(map[int64]models.Node, len(data.Nodes))
+	for _, n := range data.Nodes {
+		nodes[n.ID] = n
+	}
+
+	inflow, outflow := map[int64]float64{}, map[int64]float64{}
+	edges := map[int64][]int64{}
+	for _, f := range data.Flows {
+		_, fromOK := nodes[f.FromNodeID]
+		_, toOK := nodes[f.ToNodeID]
+		switch {
+		case !fromOK || !toOK:
+			issue(IssueError, "unknown_node", "flow references a node outside this profile", 0, f.ID)
+			continue
+		case f.FromNodeID == f.ToNodeID:
+			issue(IssueError, "self_loop", ErrSelfLoop.Error(), f.FromNodeID, f.ID)
+			continue
+		case f.Amount <= 0:
+			issue(IssueError, "invalid_amount", "flow amount must be positive", 0, f.ID)
+			continue
+		}
+		outflow[f.FromNodeID] += f.Amount
+		inflow[f.ToNodeID] += f.Amount
+		edges[f.FromNodeID] = append(edges[f.FromNodeID], f.ToNodeID)
+	}
+
+	for _, n := range data.Nodes {
+		in, out := inflow[n.ID], outflow[n.ID]
+		if n.Type == "income" {
+			in += n.Amount
+		}
+		if in == 0 && out == 0 {
+			continue
+		}
+		in, out = RoundCents(in), RoundCents(out)
+		report.Nodes = append(report.Nodes, models.NodeFlowBalance{
+			NodeID: n.ID, Label: n.Label, Type: n.Type,
+			Inflow: in, Outflow: out, Net: RoundCents(in - out),
+		})
+
+		switch {
+		case n.Type == "income" && out > in:
+			issue(IssueError, "over_allocated", fmt.Sprintf("%s allocates %.2f but only earns %.2f", n.Label, out, in), n.ID, 0)
+		case n.Type == "income" && out < in:
+			report.UnallocatedIncome += in - out
+			issue(IssueWarning, "unallocated_income", fmt.Sprintf("%.2f of %s is not allocated", in-out, n.Label), n.ID, 0)
+		case out > in:
+			issue(IssueWarning, "outflow_exceeds_inflow", fmt.Sprintf("%s sends %.2f more than it receives", n.Label, out-in), n.ID, 0)
+		}
+	}
+	report.UnallocatedIncome = RoundCents(report.UnallocatedIncome)
+
+	for _, cycle := range findCycles(edges) {
+		report.Cycles = append(report.Cycles, cycle)
+		issue(IssueWarning, "cycle", fmt.Sprintf("money flows in a loop through %d nodes", len(cycle)-1), cycle[0], 0)
+	}
+
+	return report
+}
+
+// findCycles returns one cycle per back edge found by a depth-first search,
+// each rotated to start at its smallest node ID and closed by repeating it.
+// Not every elementary cycle is listed, but every cyclic part of the graph
+// appears in at least one.
+func findCycles(edges map[int64][]int64) [][]int64 {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+
+	var starts []int64
+	for id, targets := range edges {
+		starts = append(starts, id)
+		sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	state := map[int64]int{}
+	var stack []int64
+	seen := map[string]bool{}
+	var cycles [][]int64
+
+	var visit func(id int64)
+	visit = func(id int64) {
+		state[id] = onStack
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onStack:
+				i := len(stack) - 1
+				for stack[i] != next {
+					i--
+				}
+				cycle := canonicalCycle(stack[i:])
+				if key := fmt.Sprint(cycle); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, id := range starts {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+func canonicalCycle(path []int64) []int64 {
+	low := 0
+	for i, id := range path {
+		if id < path[low] {
+			low = i
+		}
+	}
+	cycle := make([]int64, 0, len(path)+1)
+	cycle = append(cycle, path[low:]...)
+	cycle = append(cycle, path[:low]...)
+	return append(cycle, cycle[0])
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Sources of a balance snapshot
+const (
+	BalanceCreated  = "create"
+	BalanceUpdated  = "update"
+	BalanceInterest = "interest"
+	BalanceNightly  = "nightly"
+	BalanceImported = "import"
+)
+
+// Granularity of a time series
+type Granularity string
+
+const (
+	Daily   Granularity = "daily"
+	Weekly  Granularity = "weekly"
+	Monthly Granularity = "monthly"
+)
+
+func (g Granularity) Valid() bool {
+	return g == Daily || g == Weekly || g == Monthly
+}
+
+// DefaultFrom is where a series starts when the caller doesn't say:
+// 90 days, 52 weeks or 24 months back
+func (g Granularity) DefaultFrom(to time.Time) time.Time {
+	switch g {
+	case Weekly:
+		return to.AddDate(0, 0, -7*52)
+	case Monthly:
+		return to.AddDate(0, -24, 0)
+	}
+	return to.AddDate(0, 0, -90)
+}
+
+// maxSeriesPoints bounds a series (ten years of days)
+const maxSeriesPoints = 3660
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// HasBalance reports whether a node type carries a balance worth tracking
+func HasBalance(nodeType string) bool {
+	return IsAsset(nodeType) || IsLiability(nodeType) || nodeType == "goal"
+}
+
+// RecordBalance appends a balance snapshot for a node
+func RecordBalance(db execer, nodeID int64, balance float64, source string) error {
+	_, err := db.Exec(
+		"INSERT INTO node_balance_history (node_id, balance, source, recorded_at) VALUES (?, ?, ?, ?)",
+		nodeID, balance, source, time.Now().UTC().Format("2006-01-02 15:04:05"),
+	)
+	return err
+}
+
+// SnapshotBalances records every balance-carrying node once per day, so
+// series stay dense for nodes that rarely change. Returns rows written.
+func (s *Store) SnapshotBalances(now time.Time) (int, error) {
+	day := now.UTC().Format("2006-01-02")
+	result, err := s.db.Exec(`
+		INSERT INTO node_balance_history (node_id, balance, source, recorded_at)
+		SELECT n.id, n.balance, ?, ?
+		FROM nodes n
+		WHERE n.type IN ('account', 'savings', 'investment', 'goal', 'liability', 'credit_card', 'loan')
+			AND NOT EXISTS (
+				SELECT 1 FROM node_balance_history h
+				WHERE h.node_id = n.id AND h.source = ? AND date(h.recorded_at) = ?
+			)
+	`, BalanceNightly, now.UTC().Format("2006-01-02 15:04:05"), BalanceNightly, day)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// balanceEntry is one stored snapshot
+type balanceEntry struct {
+	at      time.Time
+	balance float64
+}
+
+// balanceHistory loads a profile's snapshots per node, oldest first: each
+// node's last snapshot before from, to carry its balance into the range,
+// then those from from up to to. A nodeID of 0 loads every node.
+func (s *Store) balanceHistory(profileID, nodeID int64, from, to time.Time) (map[int64][]balanceEntry, error) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC).Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`
+		SELECT h.node_id, h.balance, h.recorded_at, h.id
+		FROM node_balance_history h
+		WHERE h.id IN (
+			SELECT (
+				SELECT s.id FROM node_balance_history s
+				WHERE s.node_id = n.id AND s.recorded_at < ?
+				ORDER BY s.recorded_at DESC, s.id DESC
+				LIMIT 1
+			)
+			FROM nodes n
+			WHERE n.profile_id = ? AND (? = 0 OR n.id = ?)
+		)
+		UNION ALL
+		SELECT h.node_id, h.balance, h.recorded_at, h.id
+		FROM node_balance_history h
+		JOIN nodes n ON n.id = h.node_id
+		WHERE n.profile_id = ? AND (? = 0 OR n.id = ?)
+			AND h.recorded_at >= ? AND h.recorded_at < ?
+		ORDER BY 3, 4
+	`, start, profileID, nodeID, nodeID,
+		profileID, nodeID, nodeID, start, to.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := map[int64][]balanceEntry{}
+	for rows.Next() {
+		var id, rowID int64
+		var e balanceEntry
+		if err := rows.Scan(&id, &e.balance, &e.at, &rowID); err != nil {
+			return nil, err
+		}
+		history[id] = append(history[id], e)
+	}
+
+	return history, rows.Err()
+}
+
+// SeriesDates returns the last day of each period between from and to, with
+// the final (possibly partial) period ending on to
+func SeriesDates(g Granularity, from, to time.Time) []time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	var dates []time.Time
+	for d := from; !d.After(to) && len(dates) < maxSeriesPoints; {
+		var end time.Time
+		switch g {
+		case Weekly:
+			// Weeks end on Sunday
+			end = d.AddDate(0, 0, (7-int(d.Weekday()))%7)
+		case Monthly:
+			end = time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+		default:
+			end = d
+		}
+		if end.After(to) {
+			end = to
+		}
+		dates = append(dates, end)
+		d = end.AddDate(0, 0, 1)
+	}
+	return dates
+}
+
+// balanceCursor walks a node's snapshots forward through a series
+type balanceCursor struct {
+	entries []balanceEntry
+	next    int
+	value   float64
+	found   bool
+}
+
+// at is the latest balance recorded before the end of day, if any. Days
+// must be passed in order.
+func (c *balanceCursor) at(day time.Time) (float64, bool) {
+	cutoff := day.AddDate(0, 0, 1)
+	for c.next < len(c.entries) && c.entries[c.next].at.Before(cutoff) {
+		c.value, c.found = c.entries[c.next].balance, true
+		c.next++
+	}
+	return c.value, c.found
+}
+
+// NodeHistory is a node's balance at the end of each period. Periods before
+// the first snapshot are omitted.
+func (s *Store) NodeHistory(profileID, nodeID int64, g Granularity, from, to time.Time) ([]models.HistoryPoint, error) {
+	history, err := s.balanceHistory(profileID, nodeID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := balanceCursor{entries: history[nodeID]}
+	points := []models.HistoryPoint{}
+	for _, day := range SeriesDates(g, from, to) {
+		if balance, ok := cursor.at(day); ok {
+			points = append(points, models.HistoryPoint{Date: day.Format("2006-01-02"), Balance: balance})
+		}
+	}
+	return points, nil
+}
+
+// NetWorthHistory totals asset and liability balances at the end of each
+// period, carrying each node's last known balance forward
+func (s *Store) NetWorthHistory(profileID int64, g Granularity, from, to time.Time) ([]models.NetWorthPoint, error) {
+	nodes, err := s.Nodes(profileID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.balanceHistory(profileID, 0, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	cursors := make([]balanceCursor, len(nodes))
+	for i, n := range nodes {
+		cursors[i].entries = history[n.ID]
+	}
+
+	points := []models.NetWorthPoint{}
+	for _, day := range SeriesDates(g, from, to) {
+		p := models.NetWorthPoint{Date: day.Format("2006-01-02")}
+		for i, n := range nodes {
+			balance, ok := cursors[i].at(day)
+			if !ok {
+				continue
+			}
+			switch {
+			case IsAsset(n.Type):
+				p.Assets += balance
+			case IsLiability(n.Type):
+				p.Liabilities += balance
+			}
+		}
+		p.Assets = RoundCents(p.Assets)
+		p.Liabilities = RoundCents(p.Liabilities)
+		p.NetWorth = RoundCents(p.Assets - p.Liabilities)
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Compounding is how often accrued interest is credited to a balance
+type Compounding string
+
+const (
+	CompoundDaily   Compounding = "daily"
+	CompoundMonthly Compounding = "monthly"
+)
+
+func (c Compounding) Valid() bool {
+	return c == CompoundDaily || c == CompoundMonthly
+}
+
+// InterestBearing reports whether the accrual job credits interest to a node type
+func InterestBearing(nodeType string) bool {
+	return nodeType == "savings" || nodeType == "investment"
+}
+
+// GrowthRate is the rate earned over a number of days at an annual
+// percentage yield. APY already includes compounding, so a year of periods
+// always multiplies out to exactly 1 + APY whatever the period length.
+func GrowthRate(apy float64, days float64) float64 {
+	return math.Pow(1+apy/100, days/365) - 1
+}
+
+// MonthlyRate is the monthly equivalent of an annual percentage yield
+func MonthlyRate(apy float64) float64 {
+	return math.Pow(1+apy/100, 1.0/12) - 1
+}
+
+// nextPeriodEnd is the end of the compounding period that starts on from:
+// the next day, or the first of the next month
+func (c Compounding) nextPeriodEnd(from time.Time) time.Time {
+	if c == CompoundDaily {
+		return from.AddDate(0, 0, 1)
+	}
+	return time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// AccrueInterest credits interest to every APY-bearing node for each
+// compounding period completed since it was last accrued, recording one
+// interest_accruals row per period. A node seen for the first time starts
+// accruing from today; nothing is backdated. Returns the rows written.
+func (s *Store) AccrueInterest(now time.Time, compounding Compounding) (int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Nodes with holdings are valued from prices instead
+	rows, err := s.db.Query(`
+		SELECT id FROM nodes
+		WHERE type IN ('savings', 'investment') AND apy > 0
+			AND id NOT IN (SELECT node_id FROM holdings)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, id := range ids {
+		count, err := s.accrueNode(id, today, compounding)
+		if err != nil {
+			return written, fmt.Errorf("node %d: %w", id, err)
+		}
+		written += count
+	}
+
+	return written, nil
+}
+
+func (s *Store) accrueNode(nodeID int64, today time.Time, compounding Compounding) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Re-read inside the transaction so a concurrent balance edit isn't lost
+	var balance, apy float64
+	var through sql.NullString
+	err = tx.QueryRow("SELECT balance, apy, interest_accrued_through FROM nodes WHERE id = ?", nodeID).Scan(&balance, &apy, &through)
+	if err != nil {
+		return 0, err
+	}
+
+	if !through.Valid {
+		if _, err := tx.Exec("UPDATE nodes SET interest_accrued_through = ? WHERE id = ?", today.Format("2006-01-02"), nodeID); err != nil {
+			return 0, err
+		}
+		return 0, tx.Commit()
+	}
+
+	start, err := time.Parse("2006-01-02", DateOnly(through.String))
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for end := compounding.nextPeriodEnd(start); !end.After(today); end = compounding.nextPeriodEnd(start) {
+		if balance > 0 {
+			interest := RoundCents(balance * GrowthRate(apy, end.Sub(start).Hours()/24))
+			if interest > 0 {
+				balance = RoundCents(balance + interest)
+				_, err := tx.Exec(`
+					INSERT INTO interest_accruals (node_id, amount, balance_after, apy, period_start, period_end)
+					VALUES (?, ?, ?, ?, ?, ?)
+				`, nodeID, interest, balance, apy, start.Format("2006-01-02"), end.Format("2006-01-02"))
+				if err != nil {
+					return 0, err
+				}
+				written++
+			}
+		}
+		start = end
+	}
+
+	if _, err := tx.Exec("UPDATE nodes SET balance = ?, interest_accrued_through = ? WHERE id = ?", balance, start.Format("2006-01-02"), nodeID); err != nil {
+		return 0, err
+	}
+	if written > 0 {
+		if err := RecordBalance(tx, nodeID, balance, BalanceInterest); err != nil {
+			return 0, err
+		}
+	}
+
+	return written, tx.Commit()
+}
+
+// InterestAccruals lists a node's accruals, newest first
+func (s *Store) InterestAccruals(nodeID int64) ([]models.InterestAccrual, error) {
+	rows, err := s.db.Query(`
+		SELECT id, node_id, amount, balance_after, apy, period_start, period_end, created_at
+		FROM interest_accruals WHERE node_id = ?
+		ORDER BY period_end DESC
+	`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accruals := []models.InterestAccrual{}
+	for rows.Next() {
+		var a models.InterestAccrual
+		if err := rows.Scan(&a.ID, &a.NodeID, &a.Amount, &a.BalanceAfter, &a.APY, &a.PeriodStart, &a.PeriodEnd, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.PeriodStart = DateOnly(a.PeriodStart)
+		a.PeriodEnd = DateOnly(a.PeriodEnd)
+		accruals = append(accruals, a)
+	}
+
+	return accruals, rows.Err()
+}
+
+// MonthlyContributionNeeded is the level monthly deposit that grows current
+// to target in months at an annual percentage yield
+func MonthlyContributionNeeded(current, target, apy, months float64) float64 {
+	if months <= 0 || current >= target {
+		return 0
+	}
+
+	r := MonthlyRate(apy)
+	if r == 0 {
+		return (target - current) / months
+	}
+
+	growth := math.Pow(1+r, months)
+	shortfall := target - current*growth
+	if shortfall <= 0 {
+		return 0
+	}
+	return shortfall * r / (growth - 1)
+}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// BalanceRevalued marks a balance snapshot taken when holdings were repriced
+const BalanceRevalued = "revalue"
+
+// AssetClasses a holding can be allocated to
+var AssetClasses = []string{"stock", "bond", "fund", "cash", "crypto", "real_estate", "other"}
+
+var ErrOversold = errors.New("sell quantity exceeds the quantity held")
+
+// quantityEpsilon absorbs float error when a sell closes out a position
+const quantityEpsilon = 1e-9
+
+func ValidAssetClass(class string) bool {
+	for _, c := range AssetClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeSymbol upper-cases a ticker so "vti" and "VTI" are one holding
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// NewHoldingTransaction validates a request and fills in the cash amount.
+// The error message is safe to show to the caller.
+func NewHoldingTransaction(holdingID int64, req models.CreateHoldingTransactionRequest, today time.Time) (models.HoldingTransaction, error) {
+	t := models.HoldingTransaction{HoldingID: holdingID, Type: req.Type, Date: req.Date}
+	if t.Date == "" {
+		t.Date = today.Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", t.Date); err != nil {
+		return t, errors.New("date must be YYYY-MM-DD")
+	}
+
+	switch req.Type {
+	case "buy", "sell":
+		if req.Quantity <= 0 {
+			return t, errors.New("quantity must be positive")
+		}
+		if req.Price < 0 {
+			return t, errors.New("price cannot be negative")
+		}
+		t.Quantity, t.Price = req.Quantity, req.Price
+		t.Amount = RoundCents(req.Quantity * req.Price)
+	case "dividend":
+		if req.Amount <= 0 {
+			return t, errors.New("dividend amount must be positive")
+		}
+		t.Amount = RoundCents(req.Amount)
+	default:
+		return t, errors.New("type must be buy, sell or dividend")
+	}
+	return t, nil
+}
+
+// BuildLots replays a holding's transactions, oldest first. Buys open lots
+// and sells close them first-in, first-out; the gain on each sell is its
+// proceeds less the cost of the lots it consumed.
+func BuildLots(txs []models.HoldingTransaction) (lots []models.Lot, realized, dividends float64, err error) {
+	sorted := append([]models.HoldingTransaction{}, txs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date < sorted[j].Date })
+
+	lots = []models.Lot{}
+	for _, t := range sorted {
+		switch t.Type {
+		case "buy":
+			lots = append(lots, models.Lot{Date: t.Date, Quantity: t.Quantity, Price: t.Price})
+		case "sell":
+			remaining := t.Quantity
+			cost := 0.0
+			for remaining > quantityEpsilon && len(lots) > 0 {
+				take := min(remaining, lots[0].Quantity)
+				cost += take * lots[0].Price
+				lots[0].Quantity -= take
+				remaining -= take
+				if lots[0].Quantity <= quantityEpsilon {
+					lots = lots[1:]
+				}
+			}
+			if remaining > quantityEpsilon {
+				return nil, 0, 0, fmt.Errorf("%w on %s", ErrOversold, t.Date)
+			}
+			realized += t.Quantity*t.Price - cost
+		case "dividend":
+			dividends += t.Amount
+		}
+	}
+
+	for i := range lots {
+		lots[i].CostBasis = RoundCents(lots[i].Quantity * lots[i].Price)
+	}
+	return lots, RoundCents(realized), RoundCents(dividends), nil
+}
+
+// valueHolding fills in a holding's computed fields. The market price is the
+// latest entered price, or the latest trade price if that is more recent.
+func valueHolding(h *models.Holding, txs []models.HoldingTransaction, price models.Price, priced bool) error {
+	lots, realized, dividends, err := BuildLots(txs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", h.Symbol, err)
+	}
+	h.Lots, h.RealizedGain, h.Dividends = lots, realized, dividends
+
+	h.Quantity, h.CostBasis = 0, 0
+	for _, l := range lots {
+		h.Quantity += l.Quantity
+		h.CostBasis += l.Quantity * l.Price
+	}
+
+	if priced {
+		h.Price, h.PriceDate = price.Price, price.Date
+	}
+	for _, t := range txs {
+		if t.Type != "dividend" && t.Date > h.PriceDate {
+			h.Price, h.PriceDate = t.Price, t.Date
+		}
+	}
+
+	h.CostBasis = RoundCents(h.CostBasis)
+	h.MarketValue = RoundCents(h.Quantity * h.Price)
+	h.UnrealizedGain = RoundCents(h.MarketValue - h.CostBasis)
+	return nil
+}
+
+// holdings loads and values a profile's holdings; nodeID 0 means every node
+func (s *Store) holdings(q queryer, profileID, nodeID int64) ([]models.Holding, error) {
+	holdings, err := loadHoldings(q, profileID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := loadHoldingTransactions(q, profileID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := latestPrices(q, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := map[int64][]models.HoldingTransaction{}
+	for _, t := range all {
+		txs[t.HoldingID] = append(txs[t.HoldingID], t)
+	}
+	for i := range holdings {
+		price, ok := prices[holdings[i].Symbol]
+		if err := valueHolding(&holdings[i], txs[holdings[i].ID], price, ok); err != nil {
+			return nil, err
+		}
+	}
+	return holdings, nil
+}
+
+// holdingFilter scopes holding queries to a profile and optionally one node
+func holdingFilter(profileID, nodeID int64) (string, []any) {
+	if nodeID == 0 {
+		return "n.profile_id = ?", []any{profileID}
+	}
+	return "n.profile_id = ? AND h.node_id = ?", []any{profileID, nodeID}
+}
+
+func loadHoldings(q queryer, profileID, nodeID int64) ([]models.Holding, error) {
+	filter, args := holdingFilter(profileID, nodeID)
+	rows, err := q.Query(`
+		SELECT h.id, h.node_id, h.symbol, h.asset_class, h.created_at
+		FROM holdings h JOIN nodes n ON n.id = h.node_id
+		WHERE `+filter+`
+		ORDER BY h.node_id, h.symbol
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holdings := []models.Holding{}
+	for rows.Next() {
+		h := models.Holding{Lots: []models.Lot{}}
+		if err := rows.Scan(&h.ID, &h.NodeID, &h.Symbol, &h.AssetClass, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, h)
+	}
+	return holdings, rows.Err()
+}
+
+// loadHoldingTransactions returns transactions oldest first
+func loadHoldingTransactions(q queryer, profileID, nodeID int64) ([]models.HoldingTransaction, error) {
+	filter, args := holdingFilter(profileID, nodeID)
+	rows, err := q.Query(`
+		SELECT t.id, t.holding_id, t.type, t.quantity, t.price, t.amount, t.date, t.created_at
+		FROM holding_transactions t
+		JOIN holdings h ON h.id = t.holding_id
+		JOIN nodes n ON n.id = h.node_id
+		WHERE `+filter+`
+		ORDER BY t.date, t.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []models.HoldingTransaction{}
+	for rows.Next() {
+		t, err := scanHoldingTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// Holdings lists a node's holdings with their lots and valuation
+func (s *Store) Holdings(profileID, nodeID int64) ([]models.Holding, error) {
+	return s.holdings(s.db, profileID, nodeID)
+}
+
+func scanHoldingTransaction(row interface{ Scan(...any) error }) (models.HoldingTransaction, error) {
+	var t models.HoldingTransaction
+	err := row.Scan(&t.ID, &t.HoldingID, &t.Type, &t.Quantity, &t.Price, &t.Amount, &t.Date, &t.CreatedAt)
+	t.Date = DateOnly(t.Date)
+	return t, err
+}
+
+// HoldingTransactions lists a holding's transactions, newest first
+func (s *Store) HoldingTransactions(holdingID int64) ([]models.HoldingTransaction, error) {
+	rows, err := s.db.Query(`
+		SELECT id, holding_id, type, quantity, price, amount, date, created_at
+		FROM holding_transactions WHERE holding_id = ?
+		ORDER BY date DESC, id DESC
+	`, holdingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []models.HoldingTransaction{}
+	for rows.Next() {
+		t, err := scanHoldingTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// HoldingNode returns the investment node a holding belongs to, or
+// sql.ErrNoRows if the holding isn't in the profile
+func (s *Store) HoldingNode(profileID, holdingID int64) (int64, error) {
+	var nodeID int64
+	err := s.db.QueryRow(`
+		SELECT h.node_id FROM holdings h JOIN nodes n ON n.id = h.node_id
+		WHERE h.id = ? AND n.profile_id = ?
+	`, holdingID, profileID).Scan(&nodeID)
+	return nodeID, err
+}
+
+// AddHoldingTransaction records a buy, sell or dividend and revalues the
+// node. Sells that would take the position below zero at any point in its
+// history are rejected with ErrOversold.
+func (s *Store) AddHoldingTransaction(profileID int64, t models.HoldingTransaction) (models.HoldingTransaction, error) {
+	return t, s.changeHolding(profileID, t.HoldingID, func(tx *sql.Tx, txs []models.HoldingTransaction) error {
+		if _, _, _, err := BuildLots(append(txs, t)); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`
+			INSERT INTO holding_transactions (holding_id, type, quantity, price, amount, date)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, t.HoldingID, t.Type, t.Quantity, t.Price, t.Amount, t.Date)
+		if err != nil {
+			return err
+		}
+		t.ID, err = result.LastInsertId()
+		t.CreatedAt = time.Now()
+		return err
+	})
+}
+
+// DeleteHoldingTransaction removes a transaction and revalues the node.
+// Deleting a buy that later sells depend on fails with ErrOversold.
+func (s *Store) DeleteHoldingTransaction(profileID, holdingID, txID int64) error {
+	return s.changeHolding(profileID, holdingID, func(tx *sql.Tx, txs []models.HoldingTransaction) error {
+		kept := txs[:0:0]
+		found := false
+		for _, t := range txs {
+			if t.ID == txID {
+				found = true
+				continue
+			}
+			kept = append(kept, t)
+		}
+		if !found {
+			return sql.ErrNoRows
+		}
+		if _, _, _, err := BuildLots(kept); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM holding_transactions WHERE id = ?", txID)
+		return err
+	})
+}
+
+// changeHolding runs change against a holding's current transactions inside
+// a transaction, then revalues its node
+func (s *Store) changeHolding(profileID, holdingID int64, change func(*sql.Tx, []models.HoldingTransaction) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var nodeID int64
+	err = tx.QueryRow(`
+		SELECT h.node_id FROM holdings h JOIN nodes n ON n.id = h.node_id
+		WHERE h.id = ? AND n.profile_id = ?
+	`, holdingID, profileID).Scan(&nodeID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, holding_id, type, quantity, price, amount, date, created_at
+		FROM holding_transactions WHERE holding_id = ? ORDER BY date, id
+	`, holdingID)
+	if err != nil {
+		return err
+	}
+	var txs []models.HoldingTransaction
+	for rows.Next() {
+		t, err := scanHoldingTransaction(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		txs = append(txs, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := change(tx, txs); err != nil {
+		return err
+	}
+	if err := s.revalueNode(tx, profileID, nodeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteHolding removes a holding with its transactions and revalues the
+// node. Once its last holding is gone the node is worth nothing.
+func (s *Store) DeleteHolding(profileID, holdingID int64) error {
+	return s.changeHolding(profileID, holdingID, func(tx *sql.Tx, _ []models.HoldingTransaction) error {
+		var nodeID int64
+		if err := tx.QueryRow("SELECT node_id FROM holdings WHERE id = ?", holdingID).Scan(&nodeID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM holding_transactions WHERE holding_id = ?", holdingID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM holdings WHERE id = ?", holdingID); err != nil {
+			return err
+		}
+
+		var remaining int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM holdings WHERE node_id = ?", nodeID).Scan(&remaining); err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+		return setRevaluedBalance(tx, nodeID, 0)
+	})
+}
+
+// revalueNode sets an investment node's balance to the market value of its
+// holdings. Nodes without holdings keep their hand-entered balance.
+func (s *Store) revalueNode(tx *sql.Tx, profileID, nodeID int64) error {
+	holdings, err := s.holdings(tx, profileID, nodeID)
+	if err != nil {
+		return err
+	}
+	if len(holdings) == 0 {
+		return nil
+	}
+
+	value := 0.0
+	for _, h := range holdings {
+		value += h.MarketValue
+	}
+	return setRevaluedBalance(tx, nodeID, RoundCents(value))
+}
+
+// setRevaluedBalance sets a node's balance to its holdings' value, recording
+// it in the balance history if it changed
+func setRevaluedBalance(tx *sql.Tx, nodeID int64, value float64) error {
+	result, err := tx.Exec("UPDATE nodes SET balance = ? WHERE id = ? AND balance != ?", value, nodeID, value)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return RecordBalance(tx, nodeID, value, BalanceRevalued)
+	}
+	return nil
+}
+
+// latestPrices is the most recent entered price for each symbol
+func latestPrices(q queryer, profileID int64) (map[string]models.Price, error) {
+	rows, err := q.Query(`
+		SELECT symbol, date, price FROM prices p
+		WHERE profile_id = ? AND date = (
+			SELECT MAX(date) FROM prices WHERE profile_id = p.profile_id AND symbol = p.symbol
+		)
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := map[string]models.Price{}
+	for rows.Next() {
+		var p models.Price
+		if err := rows.Scan(&p.Symbol, &p.Date, &p.Price); err != nil {
+			return nil, err
+		}
+		p.Date = DateOnly(p.Date)
+		prices[p.Symbol] = p
+	}
+	return prices, rows.Err()
+}
+
+// Prices lists a symbol's price history, newest first
+func (s *Store) Prices(profileID int64, symbol string) ([]models.Price, error) {
+	rows, err := s.db.Query(
+		"SELECT symbol, date, price FROM prices WHERE profile_id = ? AND symbol = ? ORDER BY date DESC",
+		profileID, NormalizeSymbol(symbol),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.Price{}
+	for rows.Next() {
+		var p models.Price
+		if err := rows.Scan(&p.Symbol, &p.Date, &p.Price); err != nil {
+			return nil, err
+		}
+		p.Date = DateOnly(p.Date)
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// allPrices lists every price entered in a profile
+func (s *Store) allPrices(profileID int64) ([]models.Price, error) {
+	rows, err := s.db.Query("SELECT symbol, date, price FROM prices WHERE profile_id = ? ORDER BY symbol, date", profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.Price{}
+	for rows.Next() {
+		var p models.Price
+		if err := rows.Scan(&p.Symbol, &p.Date, &p.Price); err != nil {
+			return nil, err
+		}
+		p.Date = DateOnly(p.Date)
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// ValidatePrice normalises a price entry. The error message is safe to show
+// to the caller.
+func ValidatePrice(p *models.Price) error {
+	p.Symbol = NormalizeSymbol(p.Symbol)
+	if p.Symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if _, err := time.Parse("2006-01-02", p.Date); err != nil {
+		return errors.New("date must be YYYY-MM-DD")
+	}
+	if p.Price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	return nil
+}
+
+// SetPrices stores prices, replacing any already entered for the same symbol
+// and day, and revalues every node holding an affected symbol
+func (s *Store) SetPrices(profileID int64, prices []models.Price) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	symbols := map[string]bool{}
+	for _, p := range prices {
+		_, err := tx.Exec(`
+			INSERT INTO prices (profile_id, symbol, date, price) VALUES (?, ?, ?, ?)
+			ON CONFLICT (profile_id, symbol, date) DO UPDATE SET price = excluded.price
+		`, profileID, p.Symbol, p.Date, p.Price)
+		if err != nil {
+			return err
+		}
+		symbols[p.Symbol] = true
+	}
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT h.node_id, h.symbol FROM holdings h JOIN nodes n ON n.id = h.node_id
+		WHERE n.profile_id = ?
+	`, profileID)
+	if err != nil {
+		return err
+	}
+	nodes := map[int64]bool{}
+	for rows.Next() {
+		var nodeID int64
+		var symbol string
+		if err := rows.Scan(&nodeID, &symbol); err != nil {
+			rows.Close()
+			return err
+		}
+		if symbols[symbol] {
+			nodes[nodeID] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for nodeID := range nodes {
+		if err := s.revalueNode(tx, profileID, nodeID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ParsePriceCSV reads symbol,date,price rows. A header row is optional; if
+// present its columns may come in any order and "close" is accepted for
+// price. Errors name the offending line.
+func ParsePriceCSV(r io.Reader) ([]models.Price, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	cols := map[string]int{"symbol": 0, "date": 1, "price": 2}
+	prices := []models.Price{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 1 && isPriceHeader(record) {
+			cols = map[string]int{}
+			for i, name := range record {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "close" {
+					name = "price"
+				}
+				cols[name] = i
+			}
+			for _, name := range []string{"symbol", "date", "price"} {
+				if _, ok := cols[name]; !ok {
+					return nil, fmt.Errorf("header is missing a %s column", name)
+				}
+			}
+			continue
+		}
+
+		field := func(name string) string {
+			if i := cols[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		price, err := strconv.ParseFloat(field("price"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price %q", line, field("price"))
+		}
+		p := models.Price{Symbol: field("symbol"), Date: field("date"), Price: price}
+		if err := ValidatePrice(&p); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		prices = append(prices, p)
+	}
+
+	return prices, nil
+}
+
+func isPriceHeader(record []string) bool {
+	for _, f := range record {
+		if strings.EqualFold(strings.TrimSpace(f), "symbol") {
+			return true
+		}
+	}
+	return false
+}
+
+// InvestmentSummary totals every holding in the profile and breaks market
+// value down by asset class
+func (s *Store) InvestmentSummary(profileID int64) (models.InvestmentSummary, error) {
+	summary := models.InvestmentSummary{Allocation: []models.AssetAllocation{}}
+
+	holdings, err := s.Holdings(profileID, 0)
+	if err != nil {
+		return summary, err
+	}
+	summary.Holdings = holdings
+
+	byClass := map[string]float64{}
+	for _, h := range holdings {
+		summary.MarketValue += h.MarketValue
+		summary.CostBasis += h.CostBasis
+		summary.RealizedGain += h.RealizedGain
+		summary.Dividends += h.Dividends
+		byClass[h.AssetClass] += h.MarketValue
+	}
+	summary.MarketValue = RoundCents(summary.MarketValue)
+	summary.CostBasis = RoundCents(summary.CostBasis)
+	summary.UnrealizedGain = RoundCents(summary.MarketValue - summary.CostBasis)
+	summary.RealizedGain = RoundCents(summary.RealizedGain)
+	summary.Dividends = RoundCents(summary.Dividends)
+
+	for _, class := range AssetClasses {
+		value, ok := byClass[class]
+		if !ok {
+			continue
+		}
+		a := models.AssetAllocation{AssetClass: class, MarketValue: RoundCents(value)}
+		if summary.MarketValue > 0 {
+			a.Percent = RoundCents(value / summary.MarketValue * 100)
+		}
+		summary.Allocation = append(summary.Allocation, a)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/thejoshbq/vault-x/internal/database"
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+func buy(date string, quantity, price float64) models.HoldingTransaction {
+	return models.HoldingTransaction{Type: "buy", Date: date, Quantity: quantity, Price: price}
+}
+
+func sell(date string, quantity, price float64) models.HoldingTransaction {
+	return models.HoldingTransaction{Type: "sell", Date: date, Quantity: quantity, Price: price}
+}
+
+func dividend(date string, amount float64) models.HoldingTransaction {
+	return models.HoldingTransaction{Type: "dividend", Date: date, Amount: amount}
+}
+
+func TestBuildLots(t *testing.T) {
+	tests := []struct {
+		name      string
+		txs       []models.HoldingTransaction
+		lots      []models.Lot
+		realized  float64
+		dividends float64
+		err       error
+	}{
+		{
+			name: "buys open lots oldest first",
+			txs:  []models.HoldingTransaction{buy("2026-02-01", 5, 20), buy("2026-01-01", 10, 10)},
+			lots: []models.Lot{
+				{Date: "2026-01-01", Quantity: 10, Price: 10, CostBasis: 100},
+				{Date: "2026-02-01", Quantity: 5, Price: 20, CostBasis: 100},
+			},
+		},
+		{
+			name: "same-date buy then sell",
+			txs:  []models.HoldingTransaction{buy("2026-01-01", 10, 10), sell("2026-01-01", 4, 12)},
+			lots: []models.Lot{
+				{Date: "2026-01-01", Quantity: 6, Price: 10, CostBasis: 60},
+			},
+			realized: 8,
+		},
+		{
+			name: "partial sell spans lots first in, first out",
+			txs: []models.HoldingTransaction{
+				buy("2026-01-01", 10, 10),
+				buy("2026-02-01", 10, 20),
+				sell("2026-03-01", 15, 30),
+			},
+			lots: []models.Lot{
+				{Date: "2026-02-01", Quantity: 5, Price: 20, CostBasis: 100},
+			},
+			// 450 proceeds less 100 + 100 cost
+			realized: 250,
+		},
+		{
+			name: "selling everything closes every lot",
+			txs: []models.HoldingTransaction{
+				buy("2026-01-01", 0.1, 30),
+				buy("2026-01-02", 0.2, 30),
+				sell("2026-01-03", 0.3, 25),
+			},
+			lots:     []models.Lot{},
+			realized: -1.5,
+		},
+		{
+			name: "sell at a loss",
+			txs:  []models.HoldingTransaction{buy("2026-01-01", 10, 50), sell("2026-06-01", 10, 40)},
+			lots: []models.Lot{},
+			// 400 proceeds less 500 cost
+			realized: -100,
+		},
+		{
+			name: "over-sell",
+			txs:  []models.HoldingTransaction{buy("2026-01-01", 10, 10), sell("2026-02-01", 11, 10)},
+			err:  ErrOversold,
+		},
+		{
+			name: "sell before the buy",
+			txs:  []models.HoldingTransaction{sell("2026-01-01", 1, 10), buy("2026-02-01", 10, 10)},
+			err:  ErrOversold,
+		},
+		{
+			name: "dividends don't touch lots",
+			txs: []models.HoldingTransaction{
+				buy("2026-01-01", 10, 10),
+				dividend("2026-03-31", 4.25),
+				dividend("2026-06-30", 4.5),
+			},
+			lots: []models.Lot{
+				{Date: "2026-01-01", Quantity: 10, Price: 10, CostBasis: 100},
+			},
+			dividends: 8.75,
+		},
+		{
+			name: "no transactions",
+			lots: []models.Lot{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lots, realized, dividends, err := BuildLots(tt.txs)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(lots) != len(tt.lots) {
+				t.Fatalf("lots = %+v, want %+v", lots, tt.lots)
+			}
+			for i, l := range lots {
+				want := tt.lots[i]
+				if l.Date != want.Date || RoundCents(l.Quantity) != want.Quantity || l.Price != want.Price || l.CostBasis != want.CostBasis {
+					t.Errorf("lot %d = %+v, want %+v", i, l, want)
+				}
+			}
+			if realized != tt.realized {
+				t.Errorf("realized = %v, want %v", realized, tt.realized)
+			}
+			if dividends != tt.dividends {
+				t.Errorf("dividends = %v, want %v", dividends, tt.dividends)
+			}
+		})
+	}
+}
+
+func TestValueHolding(t *testing.T) {
+	txs := []models.HoldingTransaction{
+		buy("2026-01-01", 10, 10),
+		buy("2026-02-01", 10, 20),
+		sell("2026-03-01", 5, 25),
+		dividend("2026-04-01", 3),
+	}
+
+	tests := []struct {
+		name       string
+		price      models.Price
+		priced     bool
+		quote      float64
+		value      float64
+		unrealized float64
+	}{
+		// Cost basis is 5 @ 10 + 10 @ 20 = 250
+		{name: "newer entered price", price: models.Price{Price: 30, Date: "2026-05-01"}, priced: true, quote: 30, value: 450, unrealized: 200},
+		{name: "older entered price loses to the last trade", price: models.Price{Price: 30, Date: "2026-02-15"}, priced: true, quote: 25, value: 375, unrealized: 125},
+		{name: "no entered price", quote: 25, value: 375, unrealized: 125},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := models.Holding{Symbol: "VTI"}
+			if err := valueHolding(&h, txs, tt.price, tt.priced); err != nil {
+				t.Fatal(err)
+			}
+			if h.Quantity != 15 || h.CostBasis != 250 || h.RealizedGain != 75 || h.Dividends != 3 {
+				t.Errorf("quantity %v, cost %v, realized %v, dividends %v", h.Quantity, h.CostBasis, h.RealizedGain, h.Dividends)
+			}
+			if h.Price != tt.quote || h.MarketValue != tt.value || h.UnrealizedGain != tt.unrealized {
+				t.Errorf("price %v, value %v, unrealized %v; want %v, %v, %v", h.Price, h.MarketValue, h.UnrealizedGain, tt.quote, tt.value, tt.unrealized)
+			}
+		})
+	}
+}
+
+// newTestStore opens a migrated database with field encryption off
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "budget.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	crypt, err := fieldcrypt.Open(db, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(db, crypt)
+}
+
+func TestRevalueNode(t *testing.T) {
+	s := newTestStore(t)
+	mustExec := func(query string, args ...any) int64 {
+		t.Helper()
+		result, err := s.db.Exec(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		return id
+	}
+	mustExec("INSERT INTO users (email, password_hash) VALUES ('a@b.c', 'x')")
+	profileID := mustExec("INSERT INTO profiles (user_id, name) VALUES (1, 'Me')")
+	nodeID := mustExec("INSERT INTO nodes (profile_id, type, label, balance) VALUES (?, 'investment', 'Brokerage', 1000)", profileID)
+	emptyID := mustExec("INSERT INTO nodes (profile_id, type, label, balance) VALUES (?, 'investment', 'Hand-kept', 1234)", profileID)
+	vti := mustExec("INSERT INTO holdings (node_id, symbol) VALUES (?, 'VTI')", nodeID)
+	bnd := mustExec("INSERT INTO holdings (node_id, symbol, asset_class) VALUES (?, 'BND', 'bond')", nodeID)
+
+	balance := func(id int64) float64 {
+		t.Helper()
+		var b float64
+		if err := s.db.QueryRow("SELECT balance FROM nodes WHERE id = ?", id).Scan(&b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	snapshots := func(id int64) int {
+		t.Helper()
+		var n int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM node_balance_history WHERE node_id = ? AND source = ?", id, BalanceRevalued).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	steps := []struct {
+		name      string
+		change    func() error
+		balance   float64
+		snapshots int
+	}{
+		{
+			name: "buy revalues at the trade price",
+			change: func() error {
+				_, err := s.AddHoldingTransaction(profileID, models.HoldingTransaction{HoldingID: vti, Type: "buy", Date: "2026-01-02", Quantity: 10, Price: 200, Amount: 2000})
+				return err
+			},
+			balance:   2000,
+			snapshots: 1,
+		},
+		{
+			name: "second holding adds its value",
+			change: func() error {
+				_, err := s.AddHoldingTransaction(profileID, models.HoldingTransaction{HoldingID: bnd, Type: "buy", Date: "2026-01-03", Quantity: 20, Price: 70, Amount: 1400})
+				return err
+			},
+			balance:   3400,
+			snapshots: 2,
+		},
+		{
+			name: "newer price revalues",
+			change: func() error {
+				return s.SetPrices(profileID, []models.Price{{Symbol: "VTI", Date: "2026-03-01", Price: 250}})
+			},
+			balance:   3900,
+			snapshots: 3,
+		},
+		{
+			name: "unchanged value records no snapshot",
+			change: func() error {
+				return s.SetPrices(profileID, []models.Price{{Symbol: "VTI", Date: "2026-03-01", Price: 250}})
+			},
+			balance:   3900,
+			snapshots: 3,
+		},
+		{
+			name: "over-sell is rejected and leaves the balance",
+			change: func() error {
+				_, err := s.AddHoldingTransaction(profileID, models.HoldingTransaction{HoldingID: vti, Type: "sell", Date: "2026-03-02", Quantity: 11, Price: 250, Amount: 2750})
+				if !errors.Is(err, ErrOversold) {
+					t.Errorf("err = %v, want ErrOversold", err)
+				}
+				return nil
+			},
+			balance:   3900,
+			snapshots: 3,
+		},
+		{
+			name:      "deleting a holding drops its value",
+			change:    func() error { return s.DeleteHolding(profileID, bnd) },
+			balance:   2500,
+			snapshots: 4,
+		},
+		{
+			name:      "deleting the last holding leaves nothing",
+			change:    func() error { return s.DeleteHolding(profileID, vti) },
+			balance:   0,
+			snapshots: 5,
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if b := balance(nodeID); b != step.balance {
+			t.Errorf("%s: balance = %v, want %v", step.name, b, step.balance)
+		}
+		if n := snapshots(nodeID); n != step.snapshots {
+			t.Errorf("%s: %d revalue snapshots, want %d", step.name, n, step.snapshots)
+		}
+	}
+
+	var orphans int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM holding_transactions").Scan(&orphans); err != nil {
+		t.Fatal(err)
+	}
+	if orphans != 0 {
+		t.Errorf("%d transactions left behind by deleted holdings", orphans)
+	}
+
+	// A node without holdings keeps its hand-entered balance
+	tx, err := s.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := s.revalueNode(tx, profileID, emptyID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if b := balance(emptyID); b != 1234 {
+		t.Errorf("node without holdings: balance = %v, want 1234", b)
+	}
+}
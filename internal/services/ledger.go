@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// LedgerFormat selects the plain-text accounting dialect to render
+type LedgerFormat string
+
+const (
+	LedgerHledger   LedgerFormat = "hledger"
+	LedgerBeancount LedgerFormat = "beancount"
+)
+
+func (f LedgerFormat) Valid() bool {
+	return f == LedgerHledger || f == LedgerBeancount
+}
+
+// Extension is the conventional file extension for the format
+func (f LedgerFormat) Extension() string {
+	if f == LedgerBeancount {
+		return "beancount"
+	}
+	return "journal"
+}
+
+// unallocatedAccount funds transactions whose budget or goal has no incoming flow
+const unallocatedAccount = "Assets:Unallocated"
+
+// Root account per node type. Budget nodes get their own branch so a budget
+// and a fixed expense with the same label never share an account.
+var ledgerRoots = map[string]string{
+	"income":      "Income",
+	"account":     "Assets:Accounts",
+	"savings":     "Assets:Savings",
+	"investment":  "Assets:Investments",
+	"goal":        "Assets:Goals",
+	"expense":     "Expenses",
+	"budget":      "Expenses:Budget",
+	"liability":   "Liabilities",
+	"credit_card": "Liabilities:CreditCards",
+	"loan":        "Liabilities:Loans",
+}
+
+type ledgerEntry struct {
+	date    string
+	kind    string // "tx" or "goal-tx", used for ordering and metadata
+	id      int64
+	payee   string
+	note    string
+	account string
+	source  string
+	amount  float64
+}
+
+// ledger holds the resolved account names and journal entries for a profile
+type ledger struct {
+	accounts []string
+	opened   map[string]string // account -> open date
+	comments map[string]string // account -> origin, e.g. "node 12"
+	entries  []ledgerEntry
+}
+
+// WriteLedger renders a profile's nodes as accounts and its budget and goal
+// transactions as journal entries. Output is fully determined by the stored
+// data (no timestamps) so repeated exports diff cleanly.
+func WriteLedger(w io.Writer, data *ProfileData, format LedgerFormat, currency string) error {
+	l := buildLedger(data)
+
+	bw := bufio.NewWriter(w)
+	switch format {
+	case LedgerBeancount:
+		writeBeancount(bw, l, currency)
+	default:
+		writeHledger(bw, l, currency)
+	}
+	return bw.Flush()
+}
+
+func buildLedger(data *ProfileData) *ledger {
+	l := &ledger{
+		opened:   map[string]string{},
+		comments: map[string]string{},
+	}
+	taken := map[string]bool{}
+
+	register := func(name, date, comment string) {
+		if _, ok := l.opened[name]; !ok {
+			l.accounts = append(l.accounts, name)
+			l.opened[name] = date
+			l.comments[name] = comment
+			return
+		}
+		if date != "" && (l.opened[name] == "" || date < l.opened[name]) {
+			l.opened[name] = date
+		}
+	}
+
+	// Nodes in ID order so duplicate labels always resolve the same way
+	nodes := append([]models.Node(nil), data.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	nodeAccounts := map[int64]string{}
+	for _, n := range nodes {
+		root, ok := ledgerRoots[n.Type]
+		if !ok {
+			root = "Assets:Other"
+		}
+		name := root + ":" + accountComponent(n.Label, fmt.Sprintf("Node%d", n.ID))
+		if taken[name] {
+			name = fmt.Sprintf("%s-%d", name, n.ID)
+		}
+		taken[name] = true
+		nodeAccounts[n.ID] = name
+		register(name, n.CreatedAt.Format("2006-01-02"), fmt.Sprintf("node %d", n.ID))
+	}
+
+	// Funding source for a node is the largest flow into it
+	sources := map[int64]string{}
+	bestFlow := map[int64]models.Flow{}
+	for _, f := range data.Flows {
+		best, seen := bestFlow[f.ToNodeID]
+		if !seen || f.Amount > best.Amount || (f.Amount == best.Amount && f.ID < best.ID) {
+			bestFlow[f.ToNodeID] = f
+		}
+	}
+	for nodeID, f := range bestFlow {
+		if name, ok := nodeAccounts[f.FromNodeID]; ok {
+			sources[nodeID] = name
+		}
+	}
+
+	budgetAccounts := map[int64]string{}
+	budgetNames := map[int64]string{}
+	budgetSources := map[int64]string{}
+	budgets := append([]models.Budget(nil), data.Budgets...)
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].ID < budgets[j].ID })
+	for _, b := range budgets {
+		budgetNames[b.ID] = b.Name
+		if name, ok := nodeAccounts[b.NodeID]; ok && b.NodeID != 0 {
+			budgetAccounts[b.ID] = name
+			budgetSources[b.ID] = sources[b.NodeID]
+			continue
+		}
+		name := ledgerRoots["budget"] + ":" + accountComponent(b.Name, fmt.Sprintf("Budget%d", b.ID))
+		if taken[name] {
+			name = fmt.Sprintf("%s-b%d", name, b.ID)
+		}
+		taken[name] = true
+		budgetAccounts[b.ID] = name
+		register(name, b.CreatedAt.Format("2006-01-02"), fmt.Sprintf("budget %d", b.ID))
+	}
+
+	goalAccounts := map[int64]string{}
+	goalNames := map[int64]string{}
+	goalSources := map[int64]string{}
+	goals := append([]models.Goal(nil), data.Goals...)
+	sort.Slice(goals, func(i, j int) bool { return goals[i].ID < goals[j].ID })
+	for _, g := range goals {
+		goalNames[g.ID] = g.Name
+		if name, ok := nodeAccounts[g.NodeID]; ok && g.NodeID != 0 {
+			goalAccounts[g.ID] = name
+			goalSources[g.ID] = sources[g.NodeID]
+			continue
+		}
+		name := ledgerRoots["goal"] + ":" + accountComponent(g.Name, fmt.Sprintf("Goal%d", g.ID))
+		if taken[name] {
+			name = fmt.Sprintf("%s-g%d", name, g.ID)
+		}
+		taken[name] = true
+		goalAccounts[g.ID] = name
+		register(name, g.CreatedAt.Format("2006-01-02"), fmt.Sprintf("goal %d", g.ID))
+	}
+
+	for _, t := range data.Transactions {
+		account, ok := budgetAccounts[t.BudgetID]
+		if !ok {
+			continue
+		}
+		source := budgetSources[t.BudgetID]
+		if source == "" {
+			source = unallocatedAccount
+		}
+		register(account, t.Date, "")
+		register(source, t.Date, "")
+		l.entries = append(l.entries, ledgerEntry{
+			date: t.Date, kind: "tx", id: t.ID,
+			payee: budgetNames[t.BudgetID], note: t.Note,
+			account: account, source: source, amount: t.Amount,
+		})
+	}
+
+	for _, t := range data.GoalTransactions {
+		account, ok := goalAccounts[t.GoalID]
+		if !ok {
+			continue
+		}
+		source := goalSources[t.GoalID]
+		if source == "" {
+			source = unallocatedAccount
+		}
+		register(account, t.Date, "")
+		register(source, t.Date, "")
+		l.entries = append(l.entries, ledgerEntry{
+			date: t.Date, kind: "goal-tx", id: t.ID,
+			payee: goalNames[t.GoalID], note: t.Note,
+			account: account, source: source, amount: t.Amount,
+		})
+	}
+
+	sort.Strings(l.accounts)
+	sort.SliceStable(l.entries, func(i, j int) bool {
+		a, b := l.entries[i], l.entries[j]
+		if a.date != b.date {
+			return a.date < b.date
+		}
+		if a.kind != b.kind {
+			return a.kind > b.kind // "tx" before "goal-tx"
+		}
+		return a.id < b.id
+	})
+
+	return l
+}
+
+func writeHledger(w *bufio.Writer, l *ledger, currency string) {
+	fmt.Fprintf(w, "; vault-x export (hledger)\n\n")
+	fmt.Fprintf(w, "commodity 1,000.00 %s\n\n", currency)
+
+	for _, account := range l.accounts {
+		if comment := l.comments[account]; comment != "" {
+			fmt.Fprintf(w, "account %s  ; %s\n", account, comment)
+		} else {
+			fmt.Fprintf(w, "account %s\n", account)
+		}
+	}
+
+	for _, e := range l.entries {
+		description := hledgerText(e.payee)
+		if e.note != "" {
+			description += " | " + hledgerText(e.note)
+		}
+		fmt.Fprintf(w, "\n%s %s  ; %s:%d\n", e.date, description, e.kind, e.id)
+		fmt.Fprintf(w, "    %s  %s %s\n", e.account, formatAmount(e.amount), currency)
+		fmt.Fprintf(w, "    %s  %s %s\n", e.source, formatAmount(-e.amount), currency)
+	}
+}
+
+func writeBeancount(w *bufio.Writer, l *ledger, currency string) {
+	fmt.Fprintf(w, "; vault-x export (beancount)\n\n")
+	fmt.Fprintf(w, "option \"operating_currency\" \"%s\"\n\n", currency)
+
+	for _, account := range l.accounts {
+		date := l.opened[account]
+		if date == "" {
+			date = "1970-01-01"
+		}
+		fmt.Fprintf(w, "%s open %s %s\n", date, account, currency)
+	}
+
+	for _, e := range l.entries {
+		fmt.Fprintf(w, "\n%s * \"%s\" \"%s\"\n", e.date, beancountText(e.payee), beancountText(e.note))
+		fmt.Fprintf(w, "  vaultx-id: \"%s:%d\"\n", e.kind, e.id)
+		fmt.Fprintf(w, "  %s  %s %s\n", e.account, formatAmount(e.amount), currency)
+		fmt.Fprintf(w, "  %s  %s %s\n", e.source, formatAmount(-e.amount), currency)
+	}
+}
+
+// accountComponent turns a free-form label into a valid account segment:
+// "emergency fund (ally)" -> "Emergency-Fund-Ally". Valid for both hledger
+// and beancount, which requires an uppercase letter or digit first.
+func accountComponent(label, fallback string) string {
+	words := strings.FieldsFunc(label, func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	component := strings.Join(words, "-")
+	if component == "" {
+		return fallback
+	}
+	return component
+}
+
+func formatAmount(amount float64) string {
+	if amount == 0 {
+		amount = 0 // normalise -0
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// hledgerText strips characters that would end a description early
+func hledgerText(s string) string {
+	s = strings.NewReplacer("\n", " ", "\r", " ", ";", ",", "|", "/").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+func beancountText(s string) string {
+	s = strings.NewReplacer("\n", " ", "\r", " ", `\`, `\\`, `"`, `\"`).Replace(s)
+	return strings.TrimSpace(s)
+}
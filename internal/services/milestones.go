@@ -0,0 +1,239 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// DefaultMilestonePercents are the milestones every new goal starts with
+var DefaultMilestonePercents = []float64{25, 50, 75, 100}
+
+// AddDefaultMilestones gives a new goal the default milestones
+func AddDefaultMilestones(tx *sql.Tx, goalID int64) error {
+	for _, p := range DefaultMilestonePercents {
+		if _, err := tx.Exec("INSERT INTO goal_milestones (goal_id, percent) VALUES (?, ?)", goalID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleGoal brings a goal's lifecycle up to date after its money or target
+// changes: milestones now passed are stamped as reached on date, the goal
+// is marked completed on date at its target (and reopened below it), and
+// with rollover on, anything over the target moves to the next goal in
+// priority order. Reached milestones stay reached if the goal falls back
+// below them.
+func (s *Store) settleGoal(tx *sql.Tx, goalID int64, date string) error {
+	var profileID int64
+	var name string
+	var target, current float64
+	var rollover bool
+	var completed, archived sql.NullString
+	err := tx.QueryRow(`
+		SELECT profile_id, name, target, current, COALESCE(rollover, 0), completed_at, archived_at
+		FROM goals WHERE id = ?
+	`, goalID).Scan(&profileID, &name, &target, &current, &rollover, &completed, &archived)
+	if err != nil {
+		return err
+	}
+
+	// Half a cent of slack so percentages of odd targets still match
+	_, err = tx.Exec(`
+		UPDATE goal_milestones SET reached_at = ?
+		WHERE goal_id = ? AND reached_at IS NULL AND (
+			(percent IS NULL AND amount <= ?) OR
+			(percent IS NOT NULL AND ? > 0 AND percent * ? / 100 <= ?)
+		)
+	`, date, goalID, current+0.005, target, target, current+0.005)
+	if err != nil {
+		return err
+	}
+
+	reached := target > 0 && RoundCents(current) >= RoundCents(target)
+	switch {
+	case reached && !completed.Valid:
+		_, err = tx.Exec("UPDATE goals SET completed_at = ? WHERE id = ?", date, goalID)
+	case !reached && completed.Valid:
+		_, err = tx.Exec("UPDATE goals SET completed_at = NULL WHERE id = ?", goalID)
+	}
+	if err != nil {
+		return err
+	}
+
+	excess := RoundCents(current - target)
+	if !rollover || archived.Valid || target <= 0 || excess <= 0 {
+		return nil
+	}
+
+	var nextID int64
+	var nextName string
+	err = tx.QueryRow(`
+		SELECT id, name FROM goals
+		WHERE profile_id = ? AND id != ? AND archived_at IS NULL AND current < target
+		ORDER BY priority, deadline IS NULL, deadline, id
+		LIMIT 1
+	`, profileID, goalID).Scan(&nextID, &nextName)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.crypt.DecryptAll(&name, &nextName); err != nil {
+		return err
+	}
+	if _, err := s.ApplyGoalContribution(tx, models.GoalTransaction{
+		GoalID: goalID,
+		Amount: -excess,
+		Note:   fmt.Sprintf("Rolled over to %s", nextName),
+		Date:   date,
+		Kind:   GoalTxRollover,
+	}); err != nil {
+		return err
+	}
+	_, err = s.ApplyGoalContribution(tx, models.GoalTransaction{
+		GoalID: nextID,
+		Amount: excess,
+		Note:   fmt.Sprintf("Rolled over from %s", name),
+		Date:   date,
+		Kind:   GoalTxRollover,
+	})
+	return err
+}
+
+// SettleGoal runs settleGoal in its own transaction
+func (s *Store) SettleGoal(goalID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.settleGoal(tx, goalID, time.Now().Format("2006-01-02")); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GoalMilestones returns every milestone of the profile's goals, with the
+// thresholds of percentage milestones worked out against the goal's target
+func (s *Store) GoalMilestones(profileID int64) ([]models.GoalMilestone, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id, m.goal_id, m.percent, m.amount, g.target, m.reached_at, m.created_at
+		FROM goal_milestones m
+		JOIN goals g ON g.id = m.goal_id
+		WHERE g.profile_id = ?
+		ORDER BY m.goal_id, m.id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	milestones := []models.GoalMilestone{}
+	for rows.Next() {
+		var m models.GoalMilestone
+		var percent, amount sql.NullFloat64
+		var target float64
+		if err := rows.Scan(&m.ID, &m.GoalID, &percent, &amount, &target, &m.ReachedAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Percent = percent.Float64
+		m.Amount = amount.Float64
+		if percent.Valid {
+			m.Amount = RoundCents(target * percent.Float64 / 100)
+		}
+		milestones = append(milestones, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(milestones, func(i, j int) bool {
+		if milestones[i].GoalID != milestones[j].GoalID {
+			return milestones[i].GoalID < milestones[j].GoalID
+		}
+		return milestones[i].Amount < milestones[j].Amount
+	})
+	return milestones, nil
+}
+
+// AddGoalMilestone adds a milestone, stamping it reached straight away if
+// the goal is already past it
+func (s *Store) AddGoalMilestone(goalID int64, req models.CreateGoalMilestoneRequest) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var percent, amount any
+	if req.Percent > 0 {
+		percent = req.Percent
+	} else {
+		amount = req.Amount
+	}
+	result, err := tx.Exec("INSERT INTO goal_milestones (goal_id, percent, amount) VALUES (?, ?, ?)", goalID, percent, amount)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+
+	if err := s.settleGoal(tx, goalID, time.Now().Format("2006-01-02")); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ArchiveGoal archives or restores a goal. Archived goals keep their money
+// but drop out of the goal list, the surplus split and rollover. Returns
+// sql.ErrNoRows if the profile doesn't own the goal.
+func (s *Store) ArchiveGoal(profileID, goalID int64, archive bool) error {
+	query := "UPDATE goals SET archived_at = NULL WHERE id = ? AND profile_id = ?"
+	if archive {
+		query = "UPDATE goals SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP) WHERE id = ? AND profile_id = ?"
+	}
+	result, err := s.db.Exec(query, goalID, profileID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GoalHistory lists a goal's lifecycle in order: when it was created, each
+// milestone reached, and when it was completed and archived. Milestones and
+// completion carry the date of the money that reached them, which can fall
+// before the goal was created, so creation always comes first.
+func GoalHistory(g models.Goal) []models.GoalEvent {
+	events := []models.GoalEvent{{Type: "created", At: g.CreatedAt}}
+	for _, m := range g.Milestones {
+		if m.ReachedAt != nil {
+			events = append(events, models.GoalEvent{
+				Type:        "milestone",
+				At:          *m.ReachedAt,
+				MilestoneID: m.ID,
+				Percent:     m.Percent,
+				Amount:      m.Amount,
+			})
+		}
+	}
+	if g.CompletedAt != nil {
+		events = append(events, models.GoalEvent{Type: "completed", At: *g.CompletedAt})
+	}
+	if g.ArchivedAt != nil {
+		events = append(events, models.GoalEvent{Type: "archived", At: *g.ArchivedAt})
+	}
+
+	later := events[1:]
+	sort.SliceStable(later, func(i, j int) bool { return later[i].At.Before(later[j].At) })
+	return events
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// ReportPeriod is the bucket a spending report totals over
+type ReportPeriod string
+
+const (
+	ReportMonthly   ReportPeriod = "monthly"
+	ReportQuarterly ReportPeriod = "quarterly"
+	ReportYearly    ReportPeriod = "yearly"
+)
+
+// MaxReportPeriods caps the periods in one spending report
+const MaxReportPeriods = 120
+
+func (p ReportPeriod) Valid() bool {
+	return p == ReportMonthly || p == ReportQuarterly || p == ReportYearly
+}
+
+// perYear is the number of periods in a year
+func (p ReportPeriod) perYear() int {
+	switch p {
+	case ReportQuarterly:
+		return 4
+	case ReportYearly:
+		return 1
+	}
+	return 12
+}
+
+// index numbers periods consecutively, so the previous period is index-1
+// and the same period a year earlier is index-perYear
+func (p ReportPeriod) index(t time.Time) int {
+	month := int(t.Month()) - 1
+	return t.Year()*p.perYear() + month*p.perYear()/12
+}
+
+func (p ReportPeriod) start(index int) time.Time {
+	n := p.perYear()
+	return time.Date(index/n, time.Month(index%n*12/n+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (p ReportPeriod) label(index int) string {
+	switch p {
+	case ReportQuarterly:
+		return fmt.Sprintf("%d-Q%d", index/4, index%4+1)
+	case ReportYearly:
+		return fmt.Sprintf("%d", index)
+	}
+	return p.start(index).Format("2006-01")
+}
+
+// ReportPeriods is the number of periods a report from from to to covers
+func ReportPeriods(p ReportPeriod, from, to time.Time) int {
+	return p.index(to) - p.index(from) + 1
+}
+
+// SpendingReport totals budget spending per period from the period holding
+// from through the period holding to. Each period carries its change on the
+// one before and on the same period a year earlier, and the average of the
+// window periods ending with it; these reach back before from as needed.
+// Top movers are the budgets whose spending changed most between the last
+// two periods, largest change first.
+func (s *Store) SpendingReport(profileID int64, p ReportPeriod, from, to time.Time, window, top int) (models.SpendingReport, error) {
+	first, last := p.index(from), p.index(to)
+	earliest := first - max(p.perYear(), window-1)
+
+	budgets, err := s.Budgets(profileID)
+	if err != nil {
+		return models.SpendingReport{}, err
+	}
+
+	// Monthly totals are grouped in SQL and rolled up here; the range scan
+	// uses idx_transactions_budget_date
+	rows, err := s.db.Query(`
+		SELECT t.budget_id, strftime('%Y-%m', t.date) AS month, SUM(t.amount)
+		FROM transactions t
+		JOIN budgets b ON b.id = t.budget_id
+		WHERE b.profile_id = ? AND t.date >= ? AND t.date < ?
+		GROUP BY t.budget_id, month
+	`, profileID, p.start(earliest).Format("2006-01-02"), p.start(last+1).Format("2006-01-02"))
+	if err != nil {
+		return models.SpendingReport{}, err
+	}
+	defer rows.Close()
+
+	spent := map[int64][]float64{}
+	for _, b := range budgets {
+		spent[b.ID] = make([]float64, last-earliest+1)
+	}
+	for rows.Next() {
+		var budgetID int64
+		var month string
+		var amount float64
+		if err := rows.Scan(&budgetID, &month, &amount); err != nil {
+			return models.SpendingReport{}, err
+		}
+		t, err := time.Parse("2006-01", month)
+		series, ok := spent[budgetID]
+		if err != nil || !ok {
+			continue
+		}
+		if i := p.index(t) - earliest; i >= 0 && i < len(series) {
+			series[i] += amount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.SpendingReport{}, err
+	}
+
+	report := models.SpendingReport{
+		From:        p.start(first).Format("2006-01-02"),
+		To:          p.start(last+1).AddDate(0, 0, -1).Format("2006-01-02"),
+		Granularity: string(p),
+		Window:      window,
+		Periods:     []string{},
+		Budgets:     []models.BudgetTrend{},
+		TopMovers:   []models.BudgetMover{},
+	}
+	for i := first; i <= last; i++ {
+		report.Periods = append(report.Periods, p.label(i))
+	}
+
+	totals := make([]float64, last-earliest+1)
+	for _, b := range budgets {
+		series := spent[b.ID]
+		trend := models.BudgetTrend{BudgetID: b.ID, Name: b.Name}
+		trend.Periods = periodSpend(p, series, earliest, first, last, window)
+		for i, v := range series {
+			totals[i] += v
+			if i >= first-earliest {
+				trend.Total += v
+			}
+		}
+		trend.Total = RoundCents(trend.Total)
+		trend.Average = RoundCents(trend.Total / float64(last-first+1))
+		report.Budgets = append(report.Budgets, trend)
+
+		if n := len(trend.Periods); n >= 1 && trend.Periods[n-1].Change != 0 {
+			latest := trend.Periods[n-1]
+			report.TopMovers = append(report.TopMovers, models.BudgetMover{
+				BudgetID:      b.ID,
+				Name:          b.Name,
+				Period:        latest.Period,
+				Spent:         latest.Spent,
+				Previous:      RoundCents(latest.Spent - latest.Change),
+				Change:        latest.Change,
+				ChangePercent: latest.ChangePercent,
+			})
+		}
+	}
+	report.Totals = periodSpend(p, totals, earliest, first, last, window)
+
+	// Names may be encrypted at rest, so sort after decrypting
+	sort.SliceStable(report.Budgets, func(i, j int) bool { return report.Budgets[i].Name < report.Budgets[j].Name })
+	sort.SliceStable(report.TopMovers, func(i, j int) bool {
+		return math.Abs(report.TopMovers[i].Change) > math.Abs(report.TopMovers[j].Change)
+	})
+	if len(report.TopMovers) > top {
+		report.TopMovers = report.TopMovers[:top]
+	}
+
+	return report, nil
+}
+
+// periodSpend reads a series indexed from earliest and describes periods
+// first through last
+func periodSpend(p ReportPeriod, series []float64, earliest, first, last, window int) []models.PeriodSpend {
+	at := func(i int) float64 {
+		if i < earliest {
+			return 0
+		}
+		return series[i-earliest]
+	}
+
+	points := make([]models.PeriodSpend, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		point := models.PeriodSpend{Period: p.label(i), Spent: RoundCents(at(i))}
+
+		prev := RoundCents(at(i - 1))
+		point.Change = RoundCents(point.Spent - prev)
+		point.ChangePercent = percentChange(point.Spent, prev)
+
+		yearAgo := RoundCents(at(i - p.perYear()))
+		point.YearOverYear = RoundCents(point.Spent - yearAgo)
+		point.YearOverYearPercent = percentChange(point.Spent, yearAgo)
+
+		sum := 0.0
+		for j := i - window + 1; j <= i; j++ {
+			sum += at(j)
+		}
+		point.RollingAverage = RoundCents(sum / float64(window))
+
+		points = append(points, point)
+	}
+
+	return points
+}
+
+func percentChange(now, before float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	pct := math.Round((now-before)/math.Abs(before)*10000) / 100
+	return &pct
+}
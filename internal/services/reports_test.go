@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func mustDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestReportPeriodIndex(t *testing.T) {
+	tests := []struct {
+		period   ReportPeriod
+		date     string
+		previous string // Start of the period before
+		start    string
+		next     string // Start of the period after
+		label    string
+	}{
+		{ReportMonthly, "2026-01-15", "2025-12-01", "2026-01-01", "2026-02-01", "2026-01"},
+		{ReportMonthly, "2026-12-31", "2026-11-01", "2026-12-01", "2027-01-01", "2026-12"},
+		{ReportQuarterly, "2026-01-01", "2025-10-01", "2026-01-01", "2026-04-01", "2026-Q1"},
+		{ReportQuarterly, "2026-06-30", "2026-01-01", "2026-04-01", "2026-07-01", "2026-Q2"},
+		{ReportQuarterly, "2026-08-15", "2026-04-01", "2026-07-01", "2026-10-01", "2026-Q3"},
+		{ReportQuarterly, "2026-12-31", "2026-07-01", "2026-10-01", "2027-01-01", "2026-Q4"},
+		{ReportYearly, "2026-07-04", "2025-01-01", "2026-01-01", "2027-01-01", "2026"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period)+" "+tt.date, func(t *testing.T) {
+			i := tt.period.index(mustDate(tt.date))
+			for _, c := range []struct {
+				name  string
+				index int
+				want  string
+			}{
+				{"previous", i - 1, tt.previous},
+				{"start", i, tt.start},
+				{"next", i + 1, tt.next},
+			} {
+				if got := tt.period.start(c.index).Format("2006-01-02"); got != c.want {
+					t.Errorf("%s start = %s, want %s", c.name, got, c.want)
+				}
+			}
+			if got := tt.period.label(i); got != tt.label {
+				t.Errorf("label = %s, want %s", got, tt.label)
+			}
+
+			// The same period a year earlier is perYear periods back
+			yearAgo := mustDate(tt.date).AddDate(-1, 0, 0)
+			if got := tt.period.index(yearAgo); got != i-tt.period.perYear() {
+				t.Errorf("index a year earlier = %d, want %d", got, i-tt.period.perYear())
+			}
+			// Every day of the period maps back to it
+			if got := tt.period.index(tt.period.start(i+1).AddDate(0, 0, -1)); got != i {
+				t.Errorf("index of the last day = %d, want %d", got, i)
+			}
+		})
+	}
+}
+
+func TestReportPeriods(t *testing.T) {
+	tests := []struct {
+		period   ReportPeriod
+		from, to string
+		want     int
+	}{
+		{ReportMonthly, "2026-01-31", "2026-01-01", 1},
+		{ReportMonthly, "2025-11-15", "2026-02-01", 4},
+		{ReportQuarterly, "2025-12-31", "2026-01-01", 2},
+		{ReportYearly, "2024-06-01", "2026-01-01", 3},
+	}
+	for _, tt := range tests {
+		if got := ReportPeriods(tt.period, mustDate(tt.from), mustDate(tt.to)); got != tt.want {
+			t.Errorf("%s %s to %s: %d periods, want %d", tt.period, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
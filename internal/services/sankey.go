@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Sankey modes
+const (
+	SankeyPlanned = "planned" // Link values are the flows' amounts
+	SankeyActual  = "actual"  // Spending nodes use their transactions in the period
+)
+
+// SankeyColumns names the columns nodes are laid out in, left to right
+var SankeyColumns = []string{"Income", "Accounts", "Allocations"}
+
+// SankeyPalette is the default color of each node type
+var SankeyPalette = map[string]string{
+	"income":      "#00ff41",
+	"account":     "#00d4ff",
+	"savings":     "#bf00ff",
+	"investment":  "#ff0080",
+	"expense":     "#ffb800",
+	"budget":      "#ffb800",
+	"goal":        "#bf00ff",
+	"credit_card": "#ff3b3b",
+	"loan":        "#ff3b3b",
+	"liability":   "#ff3b3b",
+	"other":       "#71717a",
+}
+
+type SankeyOptions struct {
+	Mode     string
+	From, To time.Time // Inclusive; used in actual mode
+	MinShare float64   // Collapse nodes carrying less than this share of their column
+}
+
+// SankeyColumn places a node type: income on the left, accounts in the
+// middle and everything money is allocated to on the right
+func SankeyColumn(nodeType string) int {
+	switch nodeType {
+	case "income":
+		return 0
+	case "account":
+		return 1
+	}
+	return 2
+}
+
+// BuildSankey turns a profile's nodes and flows into a normalized graph.
+// Parallel flows are summed into one link and invalid flows (see
+// ValidateFlows) are left out. In actual mode, the spending recorded
+// against a budget or goal's node in the period replaces the planned
+// amounts flowing into it, split in proportion to those amounts.
+func BuildSankey(data *ProfileData, opts SankeyOptions) models.SankeyResponse {
+	resp := models.SankeyResponse{
+		Mode:    opts.Mode,
+		Columns: make([]models.SankeyColumn, len(SankeyColumns)),
+		Nodes:   []models.SankeyNode{},
+		Links:   []models.SankeyLink{},
+	}
+	for i, name := range SankeyColumns {
+		resp.Columns[i] = models.SankeyColumn{Index: i, Name: name}
+	}
+
+	colors := sankeyColors(data)
+	nodes := map[int64]*models.SankeyNode{}
+	income := map[int64]float64{}
+	for _, n := range data.Nodes {
+		nodes[n.ID] = &models.SankeyNode{ID: n.ID, Label: n.Label, Type: n.Type, Column: SankeyColumn(n.Type), Color: colors[n.ID]}
+		if n.Type == "income" {
+			income[n.ID] = n.Amount
+		}
+	}
+
+	type pair struct{ from, to int64 }
+	links := map[pair]*models.SankeyLink{}
+	for _, f := range data.Flows {
+		if nodes[f.FromNodeID] == nil || nodes[f.ToNodeID] == nil || f.FromNodeID == f.ToNodeID || f.Amount <= 0 {
+			continue
+		}
+		key := pair{f.FromNodeID, f.ToNodeID}
+		if links[key] == nil {
+			links[key] = &models.SankeyLink{Source: f.FromNodeID, Target: f.ToNodeID}
+		}
+		links[key].Planned += f.Amount
+	}
+	for _, l := range links {
+		l.Value = l.Planned
+	}
+
+	if opts.Mode == SankeyActual {
+		resp.From, resp.To = opts.From.Format("2006-01-02"), opts.To.Format("2006-01-02")
+		actual := actualSpending(data, opts.From, opts.To)
+
+		planned := map[int64]float64{}
+		for _, l := range links {
+			planned[l.Target] += l.Planned
+		}
+		for _, l := range links {
+			if spent, ok := actual[l.Target]; ok {
+				l.Value = spent * l.Planned / planned[l.Target]
+			}
+		}
+		// Spending with no planned flow into it still sizes the node
+		for id, spent := range actual {
+			if planned[id] == 0 && nodes[id] != nil {
+				nodes[id].Value = spent
+			}
+		}
+	}
+
+	in, out := map[int64]float64{}, map[int64]float64{}
+	for _, l := range links {
+		out[l.Source] += l.Value
+		in[l.Target] += l.Value
+	}
+	for id, n := range nodes {
+		n.Value = max(n.Value, in[id], out[id], income[id])
+	}
+
+	if opts.MinShare > 0 {
+		collapseSmallNodes(nodes, opts.MinShare)
+	}
+
+	merged := map[pair]*models.SankeyLink{}
+	for _, l := range links {
+		key := pair{sankeyTarget(nodes, l.Source), sankeyTarget(nodes, l.Target)}
+		if key.from == key.to {
+			continue
+		}
+		if merged[key] == nil {
+			merged[key] = &models.SankeyLink{Source: key.from, Target: key.to}
+		}
+		merged[key].Value += l.Value
+		merged[key].Planned += l.Planned
+	}
+
+	for _, n := range nodes {
+		if n.ID < 0 || !isCollapsed(nodes, n.ID) {
+			n.Value = RoundCents(n.Value)
+			resp.Nodes = append(resp.Nodes, *n)
+			if n.Column == 0 {
+				resp.TotalIncome += n.Value
+			}
+		}
+	}
+	sort.Slice(resp.Nodes, func(i, j int) bool {
+		a, b := resp.Nodes[i], resp.Nodes[j]
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if (a.ID < 0) != (b.ID < 0) {
+			return b.ID < 0 // "Other" last
+		}
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.ID < b.ID
+	})
+	resp.TotalIncome = RoundCents(resp.TotalIncome)
+
+	for _, l := range merged {
+		l.Value, l.Planned = RoundCents(l.Value), RoundCents(l.Planned)
+		l.Color = nodes[l.Target].Color
+		resp.Links = append(resp.Links, *l)
+	}
+	sort.Slice(resp.Links, func(i, j int) bool {
+		a, b := resp.Links[i], resp.Links[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+
+	return resp
+}
+
+// sankeyColors picks each node's color: a "color" in its metadata, then the
+// color of a budget or goal linked to it, then the type's default
+func sankeyColors(data *ProfileData) map[int64]string {
+	colors := map[int64]string{}
+	for _, g := range data.Goals {
+		if g.NodeID != 0 && g.Color != "" {
+			colors[g.NodeID] = g.Color
+		}
+	}
+	for _, b := range data.Budgets {
+		if b.NodeID != 0 && b.Color != "" {
+			colors[b.NodeID] = b.Color
+		}
+	}
+	for _, n := range data.Nodes {
+		var meta struct {
+			Color string `json:"color"`
+		}
+		if json.Unmarshal([]byte(n.Metadata), &meta) == nil && meta.Color != "" {
+			colors[n.ID] = meta.Color
+		}
+		if colors[n.ID] == "" {
+			colors[n.ID] = SankeyPalette[n.Type]
+		}
+		if colors[n.ID] == "" {
+			colors[n.ID] = SankeyPalette["other"]
+		}
+	}
+	return colors
+}
+
+// actualSpending totals budget and goal transactions in [from, to] by the
+// node their budget or goal is linked to
+func actualSpending(data *ProfileData, from, to time.Time) map[int64]float64 {
+	first, last := from.Format("2006-01-02"), to.Format("2006-01-02")
+	inPeriod := func(date string) bool {
+		date = DateOnly(date)
+		return date >= first && date <= last
+	}
+
+	budgetNodes := map[int64]int64{}
+	for _, b := range data.Budgets {
+		if b.NodeID != 0 {
+			budgetNodes[b.ID] = b.NodeID
+		}
+	}
+	goalNodes := map[int64]int64{}
+	for _, g := range data.Goals {
+		if g.NodeID != 0 {
+			goalNodes[g.ID] = g.NodeID
+		}
+	}
+
+	// Linked nodes with nothing spent in the period report zero, not the plan
+	actual := map[int64]float64{}
+	for _, nodeID := range budgetNodes {
+		actual[nodeID] = 0
+	}
+	for _, nodeID := range goalNodes {
+		actual[nodeID] = 0
+	}
+	for _, t := range data.Transactions {
+		if nodeID, ok := budgetNodes[t.BudgetID]; ok && inPeriod(t.Date) {
+			actual[nodeID] += t.Amount
+		}
+	}
+	for _, t := range data.GoalTransactions {
+		if nodeID, ok := goalNodes[t.GoalID]; ok && inPeriod(t.Date) {
+			actual[nodeID] += t.Amount
+		}
+	}
+	return actual
+}
+
+// collapseSmallNodes merges the nodes in each column that carry less than
+// minShare of the column's total into one "Other" node with ID -(column+1).
+// A column is left alone unless at least two nodes would merge.
+func collapseSmallNodes(nodes map[int64]*models.SankeyNode, minShare float64) {
+	totals := map[int]float64{}
+	for _, n := range nodes {
+		totals[n.Column] += n.Value
+	}
+
+	small := map[int][]*models.SankeyNode{}
+	for _, n := range nodes {
+		if n.Value < totals[n.Column]*minShare {
+			small[n.Column] = append(small[n.Column], n)
+		}
+	}
+
+	for column, group := range small {
+		if len(group) < 2 {
+			continue
+		}
+		other := &models.SankeyNode{ID: -int64(column + 1), Label: "Other", Type: "other", Column: column, Color: SankeyPalette["other"]}
+		for _, n := range group {
+			other.Value += n.Value
+			other.Collapsed = append(other.Collapsed, n.ID)
+		}
+		sort.Slice(other.Collapsed, func(i, j int) bool { return other.Collapsed[i] < other.Collapsed[j] })
+		nodes[other.ID] = other
+	}
+}
+
+func isCollapsed(nodes map[int64]*models.SankeyNode, id int64) bool {
+	return sankeyTarget(nodes, id) != id
+}
+
+// sankeyTarget is the node a link endpoint is drawn to: its column's
+// "Other" node if it was collapsed, else itself
+func sankeyTarget(nodes map[int64]*models.SankeyNode, id int64) int64 {
+	other := nodes[-int64(nodes[id].Column+1)]
+	if other == nil {
+		return id
+	}
+	for _, c := range other.Collapsed {
+		if c == id {
+			return other.ID
+		}
+	}
+	return id
+}
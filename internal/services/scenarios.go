@@ -0,0 +1,434 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Scenario change kinds
+const (
+	ChangeNodeAmount = "node_amount"
+	ChangeFlowAmount = "flow_amount"
+	ChangeAddFlow    = "add_flow"
+	ChangeRemoveFlow = "remove_flow"
+)
+
+var ScenarioChangeKinds = []string{ChangeNodeAmount, ChangeFlowAmount, ChangeAddFlow, ChangeRemoveFlow}
+
+// ValidateScenarioChange checks a change against the profile's current
+// nodes and flows. The error message is safe to show to the caller.
+func (s *Store) ValidateScenarioChange(profileID int64, c models.ScenarioChange) error {
+	owns := func(table string, id int64) bool {
+		var exists bool
+		s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = ? AND profile_id = ?)", id, profileID).Scan(&exists)
+		return exists
+	}
+
+	switch c.Kind {
+	case ChangeNodeAmount:
+		if c.Amount < 0 {
+			return errors.New("amount cannot be negative")
+		}
+		if !owns("nodes", c.NodeID) {
+			return errors.New("node not found")
+		}
+	case ChangeFlowAmount:
+		if c.Amount <= 0 {
+			return errors.New("flow amount must be positive")
+		}
+		if !owns("flows", c.FlowID) {
+			return errors.New("flow not found")
+		}
+	case ChangeRemoveFlow:
+		if !owns("flows", c.FlowID) {
+			return errors.New("flow not found")
+		}
+	case ChangeAddFlow:
+		if c.Amount <= 0 {
+			return errors.New("flow amount must be positive")
+		}
+		err := s.CheckFlowEndpoints(profileID, c.FromNodeID, c.ToNodeID)
+		if errors.Is(err, ErrSelfLoop) || errors.Is(err, ErrFlowNodeNotFound) {
+			return err
+		}
+		if err != nil {
+			return errors.New("database error")
+		}
+	default:
+		return fmt.Errorf("kind must be one of %s", strings.Join(ScenarioChangeKinds, ", "))
+	}
+	return nil
+}
+
+// Scenarios returns a profile's scenarios with their changes
+func (s *Store) Scenarios(profileID int64) ([]models.Scenario, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, name, description, created_at
+		FROM scenarios WHERE profile_id = ? ORDER BY id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scenarios := []models.Scenario{}
+	for rows.Next() {
+		sc, err := s.scanScenario(rows)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	live, err := s.liveIDs(s.db, profileID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range scenarios {
+		if scenarios[i].Changes, err = s.scenarioChanges(s.db, scenarios[i].ID, live); err != nil {
+			return nil, err
+		}
+	}
+
+	return scenarios, nil
+}
+
+// Scenario loads one scenario with its changes, returning sql.ErrNoRows if
+// the profile doesn't own it
+func (s *Store) Scenario(profileID, scenarioID int64) (models.Scenario, error) {
+	row := s.db.QueryRow(`
+		SELECT id, profile_id, name, description, created_at
+		FROM scenarios WHERE id = ? AND profile_id = ?
+	`, scenarioID, profileID)
+	sc, err := s.scanScenario(row)
+	if err != nil {
+		return sc, err
+	}
+
+	live, err := s.liveIDs(s.db, profileID)
+	if err != nil {
+		return sc, err
+	}
+	sc.Changes, err = s.scenarioChanges(s.db, sc.ID, live)
+	return sc, err
+}
+
+func (s *Store) scanScenario(row interface{ Scan(...any) error }) (models.Scenario, error) {
+	var sc models.Scenario
+	var description sql.NullString
+	if err := row.Scan(&sc.ID, &sc.ProfileID, &sc.Name, &description, &sc.CreatedAt); err != nil {
+		return sc, err
+	}
+	sc.Description = description.String
+	err := s.crypt.DecryptAll(&sc.Name, &sc.Description)
+	return sc, err
+}
+
+// liveIDs are the IDs of the nodes and flows a profile has now, for
+// spotting changes whose target has since been deleted
+type liveIDs struct {
+	nodes, flows map[int64]bool
+}
+
+func (s *Store) liveIDs(q queryer, profileID int64) (liveIDs, error) {
+	live := liveIDs{nodes: map[int64]bool{}, flows: map[int64]bool{}}
+	for _, t := range []struct {
+		table string
+		ids   map[int64]bool
+	}{{"nodes", live.nodes}, {"flows", live.flows}} {
+		rows, err := q.Query("SELECT id FROM "+t.table+" WHERE profile_id = ?", profileID)
+		if err != nil {
+			return live, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return live, err
+			}
+			t.ids[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return live, err
+		}
+	}
+	return live, nil
+}
+
+func (l liveIDs) stale(c models.ScenarioChange) bool {
+	switch c.Kind {
+	case ChangeNodeAmount:
+		return !l.nodes[c.NodeID]
+	case ChangeFlowAmount, ChangeRemoveFlow:
+		return !l.flows[c.FlowID]
+	case ChangeAddFlow:
+		return !l.nodes[c.FromNodeID] || !l.nodes[c.ToNodeID]
+	}
+	return true
+}
+
+func (s *Store) scenarioChanges(q queryer, scenarioID int64, live liveIDs) ([]models.ScenarioChange, error) {
+	rows, err := q.Query(`
+		SELECT id, scenario_id, kind, node_id, flow_id, from_node_id, to_node_id, amount, label, created_at
+		FROM scenario_changes WHERE scenario_id = ? ORDER BY id
+	`, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.ScenarioChange{}
+	for rows.Next() {
+		var c models.ScenarioChange
+		var nodeID, flowID, fromID, toID sql.NullInt64
+		var label sql.NullString
+		if err := rows.Scan(&c.ID, &c.ScenarioID, &c.Kind, &nodeID, &flowID, &fromID, &toID, &c.Amount, &label, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.NodeID, c.FlowID, c.FromNodeID, c.ToNodeID = nodeID.Int64, flowID.Int64, fromID.Int64, toID.Int64
+		c.Label = label.String
+		if err := s.crypt.DecryptAll(&c.Label); err != nil {
+			return nil, err
+		}
+		c.Stale = live.stale(c)
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// AddScenarioChange stores a validated change
+func (s *Store) AddScenarioChange(c models.ScenarioChange) (models.ScenarioChange, error) {
+	label := c.Label
+	if err := s.crypt.EncryptAll(&label); err != nil {
+		return c, err
+	}
+
+	// Only the columns the kind uses are stored; the rest stay NULL
+	var nodeID, flowID, fromID, toID any
+	switch c.Kind {
+	case ChangeNodeAmount:
+		nodeID = c.NodeID
+	case ChangeFlowAmount, ChangeRemoveFlow:
+		flowID = c.FlowID
+	case ChangeAddFlow:
+		fromID, toID = c.FromNodeID, c.ToNodeID
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO scenario_changes (scenario_id, kind, node_id, flow_id, from_node_id, to_node_id, amount, label)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ScenarioID, c.Kind, nodeID, flowID, fromID, toID, c.Amount, label)
+	if err != nil {
+		return c, err
+	}
+
+	c.ID, _ = result.LastInsertId()
+	c.CreatedAt = time.Now()
+	return c, nil
+}
+
+// ApplyScenario returns a copy of data with the scenario's changes applied
+// in order. Only nodes and flows are copied; the other slices are shared,
+// so the result must be treated as read-only. Flows the scenario adds get
+// negative IDs, -(change ID), so they can't collide with real flows.
+// Changes whose node or flow no longer exists are skipped.
+func ApplyScenario(data *ProfileData, sc models.Scenario) *ProfileData {
+	out := *data
+	out.Nodes = append([]models.Node{}, data.Nodes...)
+	out.Flows = append([]models.Flow{}, data.Flows...)
+
+	nodes := make(map[int64]int, len(out.Nodes))
+	for i, n := range out.Nodes {
+		nodes[n.ID] = i
+	}
+	flows := make(map[int64]int, len(out.Flows))
+	for i, f := range out.Flows {
+		flows[f.ID] = i
+	}
+	removed := map[int64]bool{}
+
+	for _, c := range sc.Changes {
+		switch c.Kind {
+		case ChangeNodeAmount:
+			if i, ok := nodes[c.NodeID]; ok {
+				out.Nodes[i].Amount = c.Amount
+			}
+		case ChangeFlowAmount:
+			if i, ok := flows[c.FlowID]; ok && !removed[c.FlowID] {
+				out.Flows[i].Amount = c.Amount
+			}
+		case ChangeRemoveFlow:
+			if _, ok := flows[c.FlowID]; ok {
+				removed[c.FlowID] = true
+			}
+		case ChangeAddFlow:
+			_, from := nodes[c.FromNodeID]
+			_, to := nodes[c.ToNodeID]
+			if from && to {
+				out.Flows = append(out.Flows, models.Flow{
+					ID:          -c.ID,
+					ProfileID:   sc.ProfileID,
+					FromNodeID:  c.FromNodeID,
+					ToNodeID:    c.ToNodeID,
+					Amount:      c.Amount,
+					Label:       c.Label,
+					IsRecurring: true,
+					CreatedAt:   c.CreatedAt,
+				})
+			}
+		}
+	}
+
+	if len(removed) > 0 {
+		kept := out.Flows[:0]
+		for _, f := range out.Flows {
+			if !removed[f.ID] {
+				kept = append(kept, f)
+			}
+		}
+		out.Flows = kept
+	}
+
+	return &out
+}
+
+// LoadScenario loads a profile's data with a scenario applied
+func (s *Store) LoadScenario(profileID, scenarioID int64) (*ProfileData, error) {
+	sc, err := s.Scenario(profileID, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Load(profileID)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyScenario(data, sc), nil
+}
+
+// CompareScenario sets a scenario's monthly cash flow and forecast balances
+// against the baseline's
+func CompareScenario(baseline, scenario *ProfileData, now time.Time, months int) models.ScenarioComparison {
+	cmp := models.ScenarioComparison{Months: months, Balances: []models.ScenarioBalanceDelta{}}
+
+	totals := func(data *ProfileData) (models.ScenarioTotals, map[int64]float64) {
+		dash := BuildDashboard(data, now)
+		t := models.ScenarioTotals{
+			TotalIncome:   RoundCents(dash.TotalIncome),
+			TotalExpenses: RoundCents(dash.TotalExpenses),
+			DebtPayments:  RoundCents(dash.DebtPayments),
+			NetSurplus:    RoundCents(dash.NetSurplus),
+		}
+		ending := map[int64]float64{}
+		for _, p := range BuildForecast(data, now, months).BalanceProjections {
+			if len(p.Months) > 0 {
+				ending[p.NodeID] = p.Months[len(p.Months)-1].Balance
+				t.ProjectedBalances += ending[p.NodeID]
+			}
+		}
+		t.ProjectedBalances = RoundCents(t.ProjectedBalances)
+		return t, ending
+	}
+
+	var baseEnding, scenEnding map[int64]float64
+	cmp.Baseline, baseEnding = totals(baseline)
+	cmp.Scenario, scenEnding = totals(scenario)
+	cmp.Difference = models.ScenarioTotals{
+		TotalIncome:       RoundCents(cmp.Scenario.TotalIncome - cmp.Baseline.TotalIncome),
+		TotalExpenses:     RoundCents(cmp.Scenario.TotalExpenses - cmp.Baseline.TotalExpenses),
+		DebtPayments:      RoundCents(cmp.Scenario.DebtPayments - cmp.Baseline.DebtPayments),
+		NetSurplus:        RoundCents(cmp.Scenario.NetSurplus - cmp.Baseline.NetSurplus),
+		ProjectedBalances: RoundCents(cmp.Scenario.ProjectedBalances - cmp.Baseline.ProjectedBalances),
+	}
+
+	for _, n := range baseline.Nodes {
+		if !InterestBearing(n.Type) {
+			continue
+		}
+		cmp.Balances = append(cmp.Balances, models.ScenarioBalanceDelta{
+			NodeID:     n.ID,
+			Label:      n.Label,
+			Baseline:   baseEnding[n.ID],
+			Scenario:   scenEnding[n.ID],
+			Difference: RoundCents(scenEnding[n.ID] - baseEnding[n.ID]),
+		})
+	}
+
+	return cmp
+}
+
+// PromoteScenario writes a scenario's changes to the real nodes and flows in
+// one transaction and deletes the scenario. Stale changes are skipped and
+// counted.
+func (s *Store) PromoteScenario(profileID, scenarioID int64) (applied, skipped int, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	tx.QueryRow("SELECT EXISTS(SELECT 1 FROM scenarios WHERE id = ? AND profile_id = ?)", scenarioID, profileID).Scan(&exists)
+	if !exists {
+		return 0, 0, sql.ErrNoRows
+	}
+
+	live, err := s.liveIDs(tx, profileID)
+	if err != nil {
+		return 0, 0, err
+	}
+	changes, err := s.scenarioChanges(tx, scenarioID, live)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, c := range changes {
+		// Earlier changes can make later ones stale, e.g. a flow removed
+		// and then re-priced
+		if c.Stale || live.stale(c) {
+			skipped++
+			continue
+		}
+
+		switch c.Kind {
+		case ChangeNodeAmount:
+			_, err = tx.Exec("UPDATE nodes SET amount = ? WHERE id = ? AND profile_id = ?", c.Amount, c.NodeID, profileID)
+		case ChangeFlowAmount:
+			_, err = tx.Exec("UPDATE flows SET amount = ? WHERE id = ? AND profile_id = ?", c.Amount, c.FlowID, profileID)
+		case ChangeRemoveFlow:
+			_, err = tx.Exec("DELETE FROM flows WHERE id = ? AND profile_id = ?", c.FlowID, profileID)
+			delete(live.flows, c.FlowID)
+		case ChangeAddFlow:
+			label := c.Label
+			if err = s.crypt.EncryptAll(&label); err == nil {
+				_, err = tx.Exec(`
+					INSERT INTO flows (profile_id, from_node_id, to_node_id, amount, label, is_recurring)
+					VALUES (?, ?, ?, ?, ?, 1)
+				`, profileID, c.FromNodeID, c.ToNodeID, c.Amount, label)
+			}
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		applied++
+	}
+
+	if _, err := tx.Exec("DELETE FROM scenario_changes WHERE scenario_id = ?", scenarioID); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM scenarios WHERE id = ?", scenarioID); err != nil {
+		return 0, 0, err
+	}
+
+	return applied, skipped, tx.Commit()
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Simulation defaults and limits
+const (
+	DefaultSimulationPaths   = 1000
+	DefaultSimulationMonths  = 120
+	DefaultSimulationSeed    = 1
+	DefaultReturnVolatility  = 15 // Annual, percent
+	DefaultIncomeVolatility  = 5  // Monthly, percent
+	DefaultExpenseVolatility = 10 // Monthly, percent
+	MaxSimulationPaths       = 5000
+	MaxSimulationMonths      = 360
+	MaxSimulationVolatility  = 100
+)
+
+// SimulationOptions configures a Monte Carlo run
+type SimulationOptions struct {
+	Paths             int
+	Months            int
+	Seed              int64
+	Volatility        float64 // Annual, percent, of investment returns
+	IncomeVolatility  float64 // Monthly, percent
+	ExpenseVolatility float64 // Monthly, percent
+}
+
+// Flow phases within a simulated month: income lands first, then spending
+// and debt payments go out, then what's left can be moved between nodes
+const (
+	phaseIncome = iota
+	phaseSpending
+	phaseTransfer
+)
+
+// Shocks a simulated flow is scaled by
+const (
+	shockNone = iota
+	shockIncome
+	shockSpending
+)
+
+type simFlow struct {
+	from, to int // Node indexes, -1 for nodes without a balance
+	amount   float64
+	phase    int
+	shock    int
+	goal     bool // Into a goal node: earmarks money rather than moving it
+}
+
+// Simulate runs Monte Carlo paths over the profile's monthly flows. Each
+// month every income flow is scaled by one shared draw around 1 (the income
+// volatility), and likewise every flow into expense and budget nodes; all
+// investment nodes earn one shared lognormal market return whose mean is
+// their APY, while savings and goal nodes earn their APY and debts accrue
+// their interest rate. Transfers can only move what the paying node holds.
+// Goal nodes mirror money held elsewhere, so flows into them earmark money
+// without taking it out of net worth, and stop when the paying node is
+// empty. Paths are reproducible from opts.Seed.
+//
+// Net worth bands cover opts.Months; goals with a later deadline are
+// simulated up to it, within MaxSimulationMonths.
+func Simulate(data *ProfileData, now time.Time, opts SimulationOptions) models.SimulationResponse {
+	start := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	resp := models.SimulationResponse{
+		Seed:              opts.Seed,
+		Paths:             opts.Paths,
+		Months:            opts.Months,
+		Volatility:        opts.Volatility,
+		IncomeVolatility:  opts.IncomeVolatility,
+		ExpenseVolatility: opts.ExpenseVolatility,
+		Bands:             []models.NetWorthBand{},
+		Goals:             []models.GoalProbability{},
+	}
+
+	// Nodes with a balance are simulated by index; liabilities hold what's owed
+	index := map[int64]int{}
+	types := map[int64]string{}
+	var initial, growth []float64
+	var owed []bool
+	var investments, assets, liabilities []int
+	for _, n := range data.Nodes {
+		types[n.ID] = n.Type
+		if !HasBalance(n.Type) {
+			continue
+		}
+		i := len(initial)
+		index[n.ID] = i
+		initial = append(initial, n.Balance)
+		owed = append(owed, IsLiability(n.Type))
+
+		rate := 0.0
+		switch {
+		case n.Type == "investment":
+			investments = append(investments, i)
+		case IsLiability(n.Type):
+			rate = n.InterestRate / 100 / 12
+		case InterestBearing(n.Type) || n.Type == "goal":
+			rate = MonthlyRate(n.APY)
+		}
+		growth = append(growth, rate)
+
+		switch {
+		case IsAsset(n.Type):
+			assets = append(assets, i)
+			resp.NetWorth += n.Balance
+		case IsLiability(n.Type):
+			liabilities = append(liabilities, i)
+			resp.NetWorth -= n.Balance
+		}
+	}
+	resp.NetWorth = RoundCents(resp.NetWorth)
+
+	// Market return parameters, per month, for each investment node
+	sigma := opts.Volatility / 100 / math.Sqrt(12)
+	drift := make([]float64, len(initial))
+	for _, n := range data.Nodes {
+		if n.Type == "investment" {
+			drift[index[n.ID]] = math.Log(1+n.APY/100)/12 - sigma*sigma/2
+		}
+	}
+
+	flows := make([]simFlow, 0, len(data.Flows))
+	for _, f := range data.Flows {
+		if !f.IsRecurring || f.Amount <= 0 {
+			continue
+		}
+		sf := simFlow{from: -1, to: -1, amount: f.Amount, phase: phaseTransfer}
+		if i, ok := index[f.FromNodeID]; ok {
+			sf.from = i
+		}
+		if i, ok := index[f.ToNodeID]; ok {
+			sf.to = i
+		}
+		switch to := types[f.ToNodeID]; {
+		case types[f.FromNodeID] == "income":
+			sf.phase, sf.shock = phaseIncome, shockIncome
+		case to == "expense" || to == "budget":
+			sf.phase, sf.shock = phaseSpending, shockSpending
+		case IsLiability(to):
+			sf.phase = phaseSpending
+		}
+		sf.goal = types[f.ToNodeID] == "goal"
+		flows = append(flows, sf)
+	}
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].phase < flows[j].phase })
+
+	type simGoal struct {
+		goal     models.Goal
+		node     int // -1 if the goal has no simulated node
+		deadline int // Month index the deadline falls in; 0 without one
+	}
+	goals := []simGoal{}
+	horizon := opts.Months
+	for _, g := range data.Goals {
+		if g.ArchivedAt != nil {
+			continue
+		}
+		sg := simGoal{goal: g, node: -1}
+		if i, ok := index[g.NodeID]; ok && g.NodeID != 0 {
+			sg.node = i
+		}
+		if d, err := time.Parse("2006-01-02", g.Deadline); err == nil {
+			sg.deadline = (d.Year()-start.Year())*12 + int(d.Month()-start.Month()) + 1
+			horizon = max(horizon, min(sg.deadline, MaxSimulationMonths))
+		}
+		goals = append(goals, sg)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	netWorth := make([][]float64, opts.Months)
+	for m := range netWorth {
+		netWorth[m] = make([]float64, opts.Paths)
+	}
+	// reached[g][p] is the month goal g is first met on path p; 0 if already
+	// met, horizon+1 if never
+	reached := make([][]int, len(goals))
+	for g := range reached {
+		reached[g] = make([]int, opts.Paths)
+	}
+
+	bal := make([]float64, len(initial))
+	for p := 0; p < opts.Paths; p++ {
+		copy(bal, initial)
+		for g, sg := range goals {
+			reached[g][p] = horizon + 1
+			if goalValue(sg.goal, sg.node, bal) >= RoundCents(sg.goal.Target) {
+				reached[g][p] = 0
+			}
+		}
+
+		for m := 1; m <= horizon; m++ {
+			// Draw in a fixed order so a seed always gives the same path
+			market := rng.NormFloat64()
+			income := math.Max(1+opts.IncomeVolatility/100*rng.NormFloat64(), 0)
+			spending := math.Max(1+opts.ExpenseVolatility/100*rng.NormFloat64(), 0)
+
+			for i, v := range bal {
+				if v > 0 {
+					bal[i] = v * (1 + growth[i])
+				}
+			}
+			for _, i := range investments {
+				if bal[i] > 0 {
+					bal[i] *= math.Exp(drift[i] + sigma*market)
+				}
+			}
+
+			for _, f := range flows {
+				amount := f.amount
+				switch f.shock {
+				case shockIncome:
+					amount *= income
+				case shockSpending:
+					amount *= spending
+				}
+				if f.phase == phaseTransfer && f.from >= 0 && !owed[f.from] {
+					amount = math.Min(amount, math.Max(bal[f.from], 0))
+				}
+				if amount <= 0 {
+					continue
+				}
+
+				switch {
+				case f.from < 0 || f.goal:
+				case owed[f.from]:
+					bal[f.from] += amount // Borrowed
+				default:
+					bal[f.from] -= amount
+				}
+				switch {
+				case f.to < 0:
+				case owed[f.to]:
+					bal[f.to] = math.Max(bal[f.to]-amount, 0)
+				default:
+					bal[f.to] += amount
+				}
+			}
+
+			if m <= opts.Months {
+				worth := 0.0
+				for _, i := range assets {
+					worth += bal[i]
+				}
+				for _, i := range liabilities {
+					worth -= bal[i]
+				}
+				netWorth[m-1][p] = worth
+			}
+			for g, sg := range goals {
+				if reached[g][p] > horizon && goalValue(sg.goal, sg.node, bal) >= RoundCents(sg.goal.Target) {
+					reached[g][p] = m
+				}
+			}
+		}
+	}
+
+	for m, values := range netWorth {
+		sort.Float64s(values)
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		resp.Bands = append(resp.Bands, models.NetWorthBand{
+			Month: start.AddDate(0, m, 0).Format("2006-01"),
+			P5:    RoundCents(percentile(values, 5)),
+			P25:   RoundCents(percentile(values, 25)),
+			P50:   RoundCents(percentile(values, 50)),
+			P75:   RoundCents(percentile(values, 75)),
+			P95:   RoundCents(percentile(values, 95)),
+			Mean:  RoundCents(mean / float64(len(values))),
+		})
+	}
+
+	for g, sg := range goals {
+		gp := models.GoalProbability{
+			GoalID:   sg.goal.ID,
+			Name:     sg.goal.Name,
+			Target:   sg.goal.Target,
+			Current:  sg.goal.Current,
+			Deadline: sg.goal.Deadline,
+		}
+		months := reached[g]
+		sort.Ints(months)
+
+		met := 0
+		for _, m := range months {
+			if m <= horizon {
+				met++
+			}
+		}
+		gp.ProbabilityEver = share(met, opts.Paths)
+
+		if sg.goal.Deadline != "" {
+			byDeadline := 0
+			for _, m := range months {
+				if m <= min(sg.deadline, horizon) {
+					byDeadline++
+				}
+			}
+			p := share(byDeadline, opts.Paths)
+			gp.Probability = &p
+		}
+
+		if median := months[(opts.Paths-1)/2]; median <= horizon {
+			gp.MedianCompletion = start.AddDate(0, max(median-1, 0), 0).Format("2006-01")
+		}
+
+		resp.Goals = append(resp.Goals, gp)
+	}
+
+	return resp
+}
+
+// goalValue is a goal's simulated amount: its node's balance, or its
+// current amount if it has no node
+func goalValue(g models.Goal, node int, bal []float64) float64 {
+	if node < 0 {
+		return RoundCents(g.Current)
+	}
+	return RoundCents(bal[node])
+}
+
+// percentile interpolates the pth percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// share is n of total as a fraction to four places
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*10000) / 10000
+}
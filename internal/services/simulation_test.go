@@ -0,0 +1,152 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{nil, 50, 0},
+		{[]float64{7}, 95, 7},
+		{[]float64{1, 2, 3, 4}, 0, 1},
+		{[]float64{1, 2, 3, 4}, 100, 4},
+		{[]float64{1, 2, 3, 4}, 50, 2.5},
+		{[]float64{1, 2, 3, 4}, 25, 1.75},
+		{[]float64{10, 20, 30, 40, 50}, 75, 40},
+		{[]float64{10, 20, 30, 40, 50}, 95, 48},
+		{[]float64{-100, 0, 100}, 5, -90},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.values, tt.p, got, tt.want)
+		}
+	}
+}
+
+func simulationProfile() *ProfileData {
+	return &ProfileData{
+		Nodes: []models.Node{
+			{ID: 1, Type: "income", Amount: 5000},
+			{ID: 2, Type: "account", Balance: 1000},
+			{ID: 3, Type: "investment", Balance: 20000, APY: 7},
+			{ID: 4, Type: "budget"},
+			{ID: 5, Type: "goal"},
+		},
+		Flows: []models.Flow{
+			{ID: 1, FromNodeID: 1, ToNodeID: 2, Amount: 5000, IsRecurring: true},
+			{ID: 2, FromNodeID: 2, ToNodeID: 4, Amount: 3500, IsRecurring: true},
+			{ID: 3, FromNodeID: 2, ToNodeID: 3, Amount: 1000, IsRecurring: true},
+			{ID: 4, FromNodeID: 2, ToNodeID: 5, Amount: 300, IsRecurring: true},
+		},
+		Goals: []models.Goal{{ID: 1, NodeID: 5, Name: "House", Target: 6000, Deadline: "2028-06-30"}},
+	}
+}
+
+func TestSimulateSeed(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	opts := SimulationOptions{Paths: 200, Months: 24, Seed: 7, Volatility: 15, IncomeVolatility: 5, ExpenseVolatility: 10}
+
+	first := Simulate(simulationProfile(), now, opts)
+	second := Simulate(simulationProfile(), now, opts)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("the same seed gave different results")
+	}
+
+	opts.Seed = 8
+	other := Simulate(simulationProfile(), now, opts)
+	if reflect.DeepEqual(first.Bands, other.Bands) {
+		t.Error("a different seed gave the same bands")
+	}
+
+	for _, b := range first.Bands {
+		if !(b.P5 <= b.P25 && b.P25 <= b.P50 && b.P50 <= b.P75 && b.P75 <= b.P95) {
+			t.Errorf("%s: bands out of order: %+v", b.Month, b)
+		}
+	}
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	// With no volatility every path is the same: the account gains 600 a
+	// month, the loan is paid down by 100 and the goal node earmarks 200
+	data := &ProfileData{
+		Nodes: []models.Node{
+			{ID: 1, Type: "income", Amount: 1000},
+			{ID: 2, Type: "account", Balance: 500},
+			{ID: 3, Type: "goal"},
+			{ID: 4, Type: "expense"},
+			{ID: 5, Type: "loan", Balance: 1000},
+		},
+		Flows: []models.Flow{
+			{ID: 1, FromNodeID: 1, ToNodeID: 2, Amount: 1000, IsRecurring: true},
+			{ID: 2, FromNodeID: 2, ToNodeID: 3, Amount: 200, IsRecurring: true},
+			{ID: 3, FromNodeID: 2, ToNodeID: 4, Amount: 300, IsRecurring: true},
+			{ID: 4, FromNodeID: 2, ToNodeID: 5, Amount: 100, IsRecurring: true},
+			{ID: 5, FromNodeID: 2, ToNodeID: 4, Amount: 999, IsRecurring: false},
+		},
+		Goals: []models.Goal{
+			{ID: 1, NodeID: 3, Name: "By March", Target: 1000, Deadline: "2027-03-31"},
+			{ID: 2, NodeID: 3, Name: "By February", Target: 1000, Deadline: "2027-02-28"},
+			{ID: 3, Name: "Stuck", Target: 100, Current: 50},
+			{ID: 4, Name: "Done", Target: 100, Current: 100},
+			{ID: 5, NodeID: 3, Name: "Archived", Target: 100, ArchivedAt: &time.Time{}},
+		},
+	}
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	resp := Simulate(data, now, SimulationOptions{Paths: 25, Months: 6, Seed: DefaultSimulationSeed})
+
+	if resp.NetWorth != -500 {
+		t.Errorf("net worth today = %v, want -500", resp.NetWorth)
+	}
+	if len(resp.Bands) != 6 {
+		t.Fatalf("got %d bands, want 6", len(resp.Bands))
+	}
+	for m, b := range resp.Bands {
+		want := -500 + 700*float64(m+1)
+		month := time.Date(2026, time.Month(11+m), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		if b.Month != month {
+			t.Errorf("band %d month = %s, want %s", m, b.Month, month)
+		}
+		if b.P5 != want || b.P25 != want || b.P50 != want || b.P75 != want || b.P95 != want || b.Mean != want {
+			t.Errorf("%s: bands %+v, want all %v", b.Month, b, want)
+		}
+	}
+
+	ptr := func(v float64) *float64 { return &v }
+	want := []struct {
+		id          int64
+		probability *float64
+		ever        float64
+		median      string
+	}{
+		{1, ptr(1), 1, "2027-03"},
+		{2, ptr(0), 1, "2027-03"},
+		{3, nil, 0, ""},
+		{4, nil, 1, "2026-11"},
+	}
+	if len(resp.Goals) != len(want) {
+		t.Fatalf("got %d goals, want %d", len(resp.Goals), len(want))
+	}
+	for i, w := range want {
+		g := resp.Goals[i]
+		if g.GoalID != w.id {
+			t.Fatalf("goal %d is %d, want %d", i, g.GoalID, w.id)
+		}
+		switch {
+		case w.probability == nil && g.Probability != nil:
+			t.Errorf("%s: probability = %v, want none", g.Name, *g.Probability)
+		case w.probability != nil && (g.Probability == nil || *g.Probability != *w.probability):
+			t.Errorf("%s: probability = %v, want %v", g.Name, g.Probability, *w.probability)
+		}
+		if g.ProbabilityEver != w.ever || g.MedianCompletion != w.median {
+			t.Errorf("%s: ever %v, median %q; want %v, %q", g.Name, g.ProbabilityEver, g.MedianCompletion, w.ever, w.median)
+		}
+	}
+}
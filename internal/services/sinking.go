@@ -0,0 +1,263 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// BalanceSinkingFund is the balance history source for bills paid from a
+// sinking fund
+const BalanceSinkingFund = "sinking_fund"
+
+// Sinking fund statuses
+const (
+	FundFunded  = "funded"
+	FundOnTrack = "on_track"
+	FundBehind  = "behind"
+)
+
+// periodMonths is how many months apart an expense falls due, or 0 for
+// periods a sinking fund doesn't cover
+func periodMonths(period string) int {
+	switch period {
+	case "quarterly":
+		return 3
+	case "annual":
+		return 12
+	}
+	return 0
+}
+
+func (s *Store) SinkingFunds(profileID int64) ([]models.SinkingFund, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, expense_id, node_id, last_paid, created_at
+		FROM sinking_funds WHERE profile_id = ? ORDER BY id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	funds := []models.SinkingFund{}
+	for rows.Next() {
+		var f models.SinkingFund
+		var lastPaid sql.NullString
+		if err := rows.Scan(&f.ID, &f.ProfileID, &f.ExpenseID, &f.NodeID, &lastPaid, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		f.LastPaid = DateOnly(lastPaid.String)
+		funds = append(funds, f)
+	}
+
+	return funds, rows.Err()
+}
+
+// ValidateSinkingFund checks that the expense is the profile's, falls due
+// quarterly or annually on a known date, and that the node is one of the
+// profile's savings nodes. Neither may already belong to a fund.
+func (s *Store) ValidateSinkingFund(profileID int64, req models.CreateSinkingFundRequest) error {
+	var period string
+	var nextDue sql.NullString
+	err := s.db.QueryRow("SELECT period, next_due FROM expenses WHERE id = ? AND profile_id = ?", req.ExpenseID, profileID).Scan(&period, &nextDue)
+	if err == sql.ErrNoRows {
+		return errors.New("expense not found")
+	}
+	if err != nil {
+		return err
+	}
+	if periodMonths(period) == 0 {
+		return errors.New("sinking funds cover quarterly and annual expenses")
+	}
+	if DateOnly(nextDue.String) == "" {
+		return errors.New("expense has no next due date")
+	}
+
+	node, err := s.Node(profileID, req.NodeID)
+	if err != nil {
+		return errors.New("node not found")
+	}
+	if node.Type != "savings" {
+		return errors.New("a sinking fund is held in a savings node")
+	}
+
+	var count int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM sinking_funds WHERE expense_id = ? OR node_id = ?", req.ExpenseID, req.NodeID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("expense or node already has a sinking fund")
+	}
+
+	return nil
+}
+
+// AssessSinkingFunds works out each fund against its expense's next bill:
+// the node balance is what's funded, and the monthly set-aside is the
+// shortfall spread over the months left before the due date. A fund is on
+// track when its balance is at least what setting the bill aside evenly
+// since the previous due date would have saved. Funds whose expense or node
+// is gone are left out.
+func AssessSinkingFunds(data *ProfileData, now time.Time) []models.SinkingFund {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	expenses := make(map[int64]models.Expense, len(data.Expenses))
+	for _, e := range data.Expenses {
+		expenses[e.ID] = e
+	}
+	balances := make(map[int64]float64, len(data.Nodes))
+	for _, n := range data.Nodes {
+		balances[n.ID] = n.Balance
+	}
+
+	funds := make([]models.SinkingFund, 0, len(data.SinkingFunds))
+	for _, f := range data.SinkingFunds {
+		e, ok := expenses[f.ExpenseID]
+		balance, found := balances[f.NodeID]
+		months := periodMonths(e.Period)
+		if !ok || !found || months == 0 {
+			continue
+		}
+		due, err := time.Parse("2006-01-02", e.NextDue)
+		if err != nil {
+			continue
+		}
+
+		f.ExpenseName = e.Name
+		f.Period = e.Period
+		f.NextDue = e.NextDue
+		f.Needed = RoundCents(e.Amount)
+		f.Funded = RoundCents(balance)
+		f.Shortfall = RoundCents(math.Max(e.Amount-balance, 0))
+		f.SteadyMonthly = RoundCents(e.Amount / float64(months))
+		if e.Amount > 0 {
+			f.Percentage = math.Min(balance/e.Amount, 1) * 100
+		}
+
+		f.MonthsRemaining = int(math.Max(math.Ceil(due.Sub(today).Hours()/24/daysPerMonth), 1))
+		f.MonthlySetAside = RoundCents(f.Shortfall / float64(f.MonthsRemaining))
+
+		start := AddMonths(due, -months)
+		elapsed := math.Min(math.Max(today.Sub(start).Hours()/due.Sub(start).Hours(), 0), 1)
+		f.Expected = RoundCents(e.Amount * elapsed)
+
+		switch {
+		case f.Shortfall == 0:
+			f.Status = FundFunded
+		case f.Funded >= f.Expected:
+			f.Status = FundOnTrack
+		default:
+			f.Status = FundBehind
+		}
+
+		funds = append(funds, f)
+	}
+
+	return funds
+}
+
+// PaySinkingFunds pays every bill that has fallen due from its sinking
+// fund and moves the expense on to its next due date, which starts the
+// fund's next cycle. The fund pays what it holds, up to the bill; the rest
+// is assumed to have come from elsewhere. Returns the bills paid.
+func (s *Store) PaySinkingFunds(now time.Time) (int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	rows, err := s.db.Query(`
+		SELECT f.id FROM sinking_funds f
+		JOIN expenses e ON e.id = f.expense_id
+		WHERE e.next_due IS NOT NULL AND e.next_due <= ?
+	`, today.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, id := range ids {
+		n, err := s.paySinkingFund(id, today)
+		if err != nil {
+			return paid, err
+		}
+		paid += n
+	}
+
+	return paid, nil
+}
+
+func (s *Store) paySinkingFund(fundID int64, today time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Re-read inside the transaction so a concurrent run can't pay twice
+	var expenseID, nodeID int64
+	var amount, balance float64
+	var period string
+	var nextDue sql.NullString
+	err = tx.QueryRow(`
+		SELECT e.id, e.amount, e.period, e.next_due, n.id, n.balance
+		FROM sinking_funds f
+		JOIN expenses e ON e.id = f.expense_id
+		JOIN nodes n ON n.id = f.node_id
+		WHERE f.id = ?
+	`, fundID).Scan(&expenseID, &amount, &period, &nextDue, &nodeID, &balance)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	months := periodMonths(period)
+	due, err := time.Parse("2006-01-02", DateOnly(nextDue.String))
+	if err != nil || months == 0 {
+		return 0, nil
+	}
+
+	paid := 0
+	var last time.Time
+	for !due.After(today) {
+		balance -= math.Min(amount, math.Max(balance, 0))
+		last = due
+		due = AddMonths(due, months)
+		paid++
+	}
+	if paid == 0 {
+		return 0, nil
+	}
+
+	balance = RoundCents(balance)
+	if _, err := tx.Exec("UPDATE nodes SET balance = ? WHERE id = ?", balance, nodeID); err != nil {
+		return 0, err
+	}
+	if err := RecordBalance(tx, nodeID, balance, BalanceSinkingFund); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE expenses SET next_due = ? WHERE id = ?", due.Format("2006-01-02"), expenseID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE sinking_funds SET last_paid = ? WHERE id = ?", last.Format("2006-01-02"), fundID); err != nil {
+		return 0, err
+	}
+
+	return paid, tx.Commit()
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/thejoshbq/vault-x/internal/fieldcrypt"
+	"github.com/thejoshbq/vault-x/internal/models"
+)
+
+// Store loads whole-profile data sets for services that need the full graph.
+// Sensitive text columns are decrypted on the way out.
+type Store struct {
+	db    *sql.DB
+	crypt *fieldcrypt.Keyring
+}
+
+func NewStore(db *sql.DB, crypt *fieldcrypt.Keyring) *Store {
+	return &Store{db: db, crypt: crypt}
+}
+
+// ProfileData is everything stored for a single profile
+type ProfileData struct {
+	Nodes            []models.Node
+	Flows            []models.Flow
+	Budgets          []models.Budget
+	Transactions     []models.Transaction
+	Goals            []models.Goal
+	GoalTransactions []models.GoalTransaction
+	Expenses         []models.Expense
+	SinkingFunds     []models.SinkingFund
+}
+
+// Load fetches all data for a profile
+func (s *Store) Load(profileID int64) (*ProfileData, error) {
+	var data ProfileData
+	var err error
+
+	if data.Nodes, err = s.Nodes(profileID); err != nil {
+		return nil, err
+	}
+	if data.Flows, err = s.Flows(profileID); err != nil {
+		return nil, err
+	}
+	if data.Budgets, err = s.Budgets(profileID); err != nil {
+		return nil, err
+	}
+	if data.Transactions, err = s.Transactions(profileID); err != nil {
+		return nil, err
+	}
+	if data.Goals, err = s.Goals(profileID); err != nil {
+		return nil, err
+	}
+	if data.GoalTransactions, err = s.GoalTransactions(profileID); err != nil {
+		return nil, err
+	}
+	if data.Expenses, err = s.Expenses(profileID); err != nil {
+		return nil, err
+	}
+	if data.SinkingFunds, err = s.SinkingFunds(profileID); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+const nodeColumns = `id, profile_id, type, label, institution, amount, balance, apy, budgeted, goal, metadata, sort_order, created_at,
+	principal, interest_rate, term_months, minimum_payment`
+
+func (s *Store) Nodes(profileID int64) ([]models.Node, error) {
+	rows, err := s.db.Query(`SELECT `+nodeColumns+` FROM nodes WHERE profile_id = ? ORDER BY sort_order, id`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []models.Node{}
+	for rows.Next() {
+		n, err := s.scanNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes, rows.Err()
+}
+
+// Node loads a single node, returning sql.ErrNoRows if the profile doesn't own it
+func (s *Store) Node(profileID, nodeID int64) (models.Node, error) {
+	row := s.db.QueryRow(`SELECT `+nodeColumns+` FROM nodes WHERE id = ? AND profile_id = ?`, nodeID, profileID)
+	return s.scanNode(row)
+}
+
+func (s *Store) scanNode(row interface{ Scan(...any) error }) (models.Node, error) {
+	var n models.Node
+	var institution, metadata sql.NullString
+	if err := row.Scan(&n.ID, &n.ProfileID, &n.Type, &n.Label, &institution, &n.Amount, &n.Balance, &n.APY, &n.Budgeted, &n.Goal, &metadata, &n.SortOrder, &n.CreatedAt,
+		&n.Principal, &n.InterestRate, &n.TermMonths, &n.MinimumPayment); err != nil {
+		return n, err
+	}
+	n.Institution = institution.String
+	n.Metadata = metadata.String
+	err := s.crypt.DecryptAll(&n.Label, &n.Institution)
+	return n, err
+}
+
+func (s *Store) Flows(profileID int64) ([]models.Flow, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, from_node_id, to_node_id, amount, label, is_recurring, anchor_date, created_at
+		FROM flows WHERE profile_id = ? ORDER BY id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flows := []models.Flow{}
+	for rows.Next() {
+		var f models.Flow
+		var label, anchor sql.NullString
+		if err := rows.Scan(&f.ID, &f.ProfileID, &f.FromNodeID, &f.ToNodeID, &f.Amount, &label, &f.IsRecurring, &anchor, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		f.Label = label.String
+		f.AnchorDate = DateOnly(anchor.String)
+		if err := s.crypt.DecryptAll(&f.Label); err != nil {
+			return nil, err
+		}
+		flows = append(flows, f)
+	}
+
+	return flows, rows.Err()
+}
+
+func (s *Store) Budgets(profileID int64) ([]models.Budget, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, node_id, name, budgeted, period, color, created_at
+		FROM budgets WHERE profile_id = ? ORDER BY id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		var b models.Budget
+		var nodeID sql.NullInt64
+		if err := rows.Scan(&b.ID, &b.ProfileID, &nodeID, &b.Name, &b.Budgeted, &b.Period, &b.Color, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		b.NodeID = nodeID.Int64
+		if err := s.crypt.DecryptAll(&b.Name); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	return budgets, rows.Err()
+}
+
+// Transactions returns every budget transaction for the profile, oldest first
+func (s *Store) Transactions(profileID int64) ([]models.Transaction, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.budget_id, t.amount, t.note, t.payee, t.date, t.created_at
+		FROM transactions t
+		JOIN budgets b ON b.id = t.budget_id
+		WHERE b.profile_id = ?
+		ORDER BY t.date, t.id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		var note, payee sql.NullString
+		if err := rows.Scan(&t.ID, &t.BudgetID, &t.Amount, &note, &payee, &t.Date, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.Note, t.Payee = note.String, payee.String
+		t.Date = DateOnly(t.Date)
+		if err := s.crypt.DecryptAll(&t.Note, &t.Payee); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+func (s *Store) Goals(profileID int64) ([]models.Goal, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, node_id, name, target, current, deadline, priority, color,
+			COALESCE(rollover, 0), created_at, completed_at, archived_at
+		FROM goals WHERE profile_id = ? ORDER BY priority, id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		var g models.Goal
+		var deadline sql.NullString
+		var nodeID sql.NullInt64
+		if err := rows.Scan(&g.ID, &g.ProfileID, &nodeID, &g.Name, &g.Target, &g.Current, &deadline, &g.Priority, &g.Color,
+			&g.Rollover, &g.CreatedAt, &g.CompletedAt, &g.ArchivedAt); err != nil {
+			return nil, err
+		}
+		g.NodeID = nodeID.Int64
+		g.Deadline = DateOnly(deadline.String)
+		if err := s.crypt.DecryptAll(&g.Name); err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	milestones, err := s.GoalMilestones(profileID)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int64]int, len(goals))
+	for i, g := range goals {
+		index[g.ID] = i
+		goals[i].Milestones = []models.GoalMilestone{}
+	}
+	for _, m := range milestones {
+		if i, ok := index[m.GoalID]; ok {
+			goals[i].Milestones = append(goals[i].Milestones, m)
+		}
+	}
+
+	return goals, nil
+}
+
+// GoalTransactions returns every goal contribution for the profile, oldest first
+func (s *Store) GoalTransactions(profileID int64) ([]models.GoalTransaction, error) {
+	rows, err := s.db.Query(`
+		SELECT gt.id, gt.goal_id, gt.amount, gt.note, gt.date, gt.created_at, COALESCE(gt.flow_id, 0), COALESCE(gt.kind, 'contribution')
+		FROM goal_transactions gt
+		JOIN goals g ON g.id = gt.goal_id
+		WHERE g.profile_id = ?
+		ORDER BY gt.date, gt.id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.GoalTransaction{}
+	for rows.Next() {
+		var t models.GoalTransaction
+		var note sql.NullString
+		if err := rows.Scan(&t.ID, &t.GoalID, &t.Amount, &note, &t.Date, &t.CreatedAt, &t.FlowID, &t.Kind); err != nil {
+			return nil, err
+		}
+		t.Note = note.String
+		t.Date = DateOnly(t.Date)
+		if err := s.crypt.DecryptAll(&t.Note); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+func (s *Store) Expenses(profileID int64) ([]models.Expense, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, name, amount, period, category, type, flag, next_due, created_at
+		FROM expenses WHERE profile_id = ? ORDER BY id
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []models.Expense{}
+	for rows.Next() {
+		var e models.Expense
+		var category, flag, nextDue sql.NullString
+		if err := rows.Scan(&e.ID, &e.ProfileID, &e.Name, &e.Amount, &e.Period, &category, &e.Type, &flag, &nextDue, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Category = category.String
+		e.Flag = flag.String
+		e.NextDue = DateOnly(nextDue.String)
+		if err := s.crypt.DecryptAll(&e.Name); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+
+	return expenses, rows.Err()
+}
+
+// DateOnly trims a DATE column value to YYYY-MM-DD.
+// The sqlite driver hands DATE columns back as full RFC3339 timestamps.
+func DateOnly(s string) string {
+	// An empty DATE comes back from the driver as the zero time
+	if strings.HasPrefix(s, "0001-01-01") {
+		return ""
+	}
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
@@ -29,16 +29,19 @@ const NavItem = ({ icon: Icon, label, active, onClick }) => (
 const DashboardView = ({ profileId }) => {
   const [nodes, setNodes] = useState([]);
   const [flows, setFlows] = useState([]);
+  const [graph, setGraph] = useState(null);
   const [loading, setLoading] = useState(true);
 
   const loadData = async () => {
     try {
-      const [nodesData, flowsData] = await Promise.all([
+      const [nodesData, flowsData, graphData] = await Promise.all([
         api.get(`/profiles/${profileId}/nodes`),
         api.get(`/profiles/${profileId}/flows`),
+        api.get(`/profiles/${profileId}/sankey`),
       ]);
       setNodes(nodesData || []);
       setFlows(flowsData || []);
+      setGraph(graphData);
     } catch (err) {
       console.error('Failed to load dashboard data:', err);
     } finally {
@@ -104,7 +107,7 @@ const DashboardView = ({ profileId }) => {
           <h2 className="text-zinc-300 font-medium">Cash Flow</h2>
           <span className="text-xs text-zinc-500 font-mono">HOVER FOR DETAILS</span>
         </div>
-        <SankeyDiagram nodes={nodes} flows={flows} />
+        <SankeyDiagram graph={graph} />
       </div>
     </div>
   );
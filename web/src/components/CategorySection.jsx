@@ -7,9 +7,21 @@ const colorClasses = {
   red: 'text-red-400',
   amber: 'text-amber-400',
   purple: 'text-purple-400',
-  cyan: 'text-cyan-400'
+  cyan: 'text-cyan-400',
+  rose: 'text-rose-400'
 };
 
+const typeLabels = {
+  account: 'Checking',
+  savings: 'Savings',
+  investment: 'Investment',
+  credit_card: 'Credit Card',
+  loan: 'Loan',
+  liability: 'Other Liability'
+};
+
+const textFields = ['label', 'institution'];
+
 export const CategorySection = ({
   category,
   categoryNodes,
@@ -86,6 +98,16 @@ export const CategorySection = ({
                             <span className="text-zinc-600">apy:</span> {node.apy}%
                           </span>
                         )}
+                        {node.interest_rate > 0 && (
+                          <span className="text-rose-400">
+                            <span className="text-zinc-600">rate:</span> {node.interest_rate}%
+                          </span>
+                        )}
+                        {node.minimum_payment > 0 && (
+                          <span className="text-rose-400">
+                            <span className="text-zinc-600">min:</span> ${node.minimum_payment.toFixed(2)}
+                          </span>
+                        )}
                       </div>
                     </div>
                     <div className="flex gap-1">
@@ -136,12 +158,13 @@ export const CategorySection = ({
               <div>
                 <label className="block text-xs text-zinc-500 mb-1">Account Type</label>
                 <select
-                  value={formData.accountType || 'savings'}
+                  value={formData.accountType || category.types[0]}
                   onChange={(e) => setFormData({ ...formData, accountType: e.target.value })}
                   className="w-full bg-zinc-950 border border-zinc-700 rounded px-3 py-2 text-sm"
                 >
-                  <option value="savings">Savings</option>
-                  <option value="investment">Investment</option>
+                  {category.types.map((type) => (
+                    <option key={type} value={type}>{typeLabels[type] || type}</option>
+                  ))}
                 </select>
               </div>
             )}
@@ -151,8 +174,8 @@ export const CategorySection = ({
                   {category.labels?.[field] || field.charAt(0).toUpperCase() + field.slice(1)}
                 </label>
                 <input
-                  type={field === 'amount' || field === 'balance' || field === 'apy' ? 'number' : 'text'}
-                  step={field === 'amount' || field === 'balance' || field === 'apy' ? '0.01' : undefined}
+                  type={textFields.includes(field) ? 'text' : 'number'}
+                  step={textFields.includes(field) || field === 'term_months' ? undefined : '0.01'}
                   value={formData[field] || ''}
                   onChange={(e) => setFormData({ ...formData, [field]: e.target.value })}
                   className="w-full bg-zinc-950 border border-zinc-700 rounded px-3 py-2 text-sm"
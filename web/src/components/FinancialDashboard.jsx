@@ -667,11 +667,14 @@ const AlertsPanel = ({ data }) => {
 // ============================================
 // DATA TRANSFORMATION UTILITIES
 // ============================================
+const liabilityTypes = ['credit_card', 'loan', 'liability'];
+
 const transformNodesToFinancialData = (nodes, flows) => {
   // Group nodes by type
   const incomeNodes = nodes.filter(n => n.type === 'income');
   const expenseNodes = nodes.filter(n => n.type === 'expense');
   const assetNodes = nodes.filter(n => ['savings', 'investment'].includes(n.type));
+  const liabilityNodes = nodes.filter(n => liabilityTypes.includes(n.type));
 
   // Transform income (trend set to 0 as per user decision)
   const income = incomeNodes.map(node => ({
@@ -739,6 +742,13 @@ const transformNodesToFinancialData = (nodes, flows) => {
     return asset;
   });
 
+  // Transform liabilities (balance is the amount owed)
+  const liabilities = liabilityNodes.map(node => ({
+    name: node.label,
+    balance: node.balance || 0,
+    apr: node.interest_rate || 0
+  }));
+
   // Transform cash flows
   const cashFlow = {
     operating: { inflowTotal: 0, outflowTotal: 0, inflows: [], outflows: [] },
@@ -754,7 +764,9 @@ const transformNodesToFinancialData = (nodes, flows) => {
 
     // Categorize flow
     let category = 'operating';
-    if (['savings', 'investment'].includes(toNode.type) ||
+    if (liabilityTypes.includes(toNode.type)) {
+      category = 'financing';
+    } else if (['savings', 'investment'].includes(toNode.type) ||
         ['savings', 'investment'].includes(fromNode.type)) {
       category = 'investing';
     }
@@ -779,7 +791,7 @@ const transformNodesToFinancialData = (nodes, flows) => {
     income,
     expenses,
     assets,
-    liabilities: [], // No liability nodes yet
+    liabilities,
     cashFlow
   };
 };
@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { DollarSign, TrendingUp, CreditCard, Repeat, Wallet } from 'lucide-react';
+import { DollarSign, TrendingUp, CreditCard, Repeat, Wallet, Landmark } from 'lucide-react';
 import { api } from '../lib/api';
 import { PageHeader } from './PageHeader';
 import { CategorySection } from './CategorySection';
@@ -89,6 +89,22 @@ export const NodeManagement = ({ profileId, onUpdate }) => {
       color: 'cyan',
       fields: ['label', 'institution', 'balance', 'apy'],
       labels: { balance: 'Current Balance', institution: 'Financial Institution', apy: 'APY %' }
+    },
+    {
+      id: 'liabilities',
+      title: 'LIABILITIES',
+      icon: Landmark,
+      types: ['credit_card', 'loan', 'liability'],
+      color: 'rose',
+      fields: ['label', 'institution', 'balance', 'interest_rate', 'minimum_payment', 'principal', 'term_months'],
+      labels: {
+        balance: 'Amount Owed',
+        institution: 'Lender',
+        interest_rate: 'Interest Rate %',
+        minimum_payment: 'Minimum Payment',
+        principal: 'Original Principal',
+        term_months: 'Term (months)'
+      }
     }
   ];
 
@@ -101,6 +117,10 @@ export const NodeManagement = ({ profileId, onUpdate }) => {
         amount: parseFloat(formData.amount) || 0,
         balance: parseFloat(formData.balance) || 0,
         apy: parseFloat(formData.apy) || 0,
+        principal: parseFloat(formData.principal) || 0,
+        interest_rate: parseFloat(formData.interest_rate) || 0,
+        term_months: parseInt(formData.term_months, 10) || 0,
+        minimum_payment: parseFloat(formData.minimum_payment) || 0,
         metadata: JSON.stringify(category.metadata || {})
       };
 
@@ -154,6 +174,10 @@ export const NodeManagement = ({ profileId, onUpdate }) => {
       amount: node.amount || 0,
       balance: node.balance || 0,
       apy: node.apy || 0,
+      principal: node.principal || 0,
+      interest_rate: node.interest_rate || 0,
+      term_months: node.term_months || 0,
+      minimum_payment: node.minimum_payment || 0,
       accountType: node.type
     });
   };
@@ -167,6 +191,10 @@ export const NodeManagement = ({ profileId, onUpdate }) => {
       amount: 0,
       balance: 0,
       apy: 0,
+      principal: 0,
+      interest_rate: 0,
+      term_months: 0,
+      minimum_payment: 0,
       accountType: category.types ? category.types[0] : category.type
     });
   };
@@ -1,6 +1,12 @@
-import React, { useState, useEffect } from 'react';
-
-export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
+import React, { useState, useEffect, useMemo } from 'react';
+
+// Renders the server-computed graph from GET /profiles/:id/sankey
+export const SankeyDiagram = ({ graph, onNodeClick }) => {
+  const nodes = graph?.nodes || [];
+  const flows = useMemo(
+    () => (graph?.links || []).map(l => ({ from_node_id: l.source, to_node_id: l.target, amount: l.value })),
+    [graph]
+  );
   const [particles, setParticles] = useState([]);
   const [hoveredNode, setHoveredNode] = useState(null);
   const [hoveredFlow, setHoveredFlow] = useState(null);
@@ -49,20 +55,13 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
     );
   }
 
-  // Calculate total inflow amount for a node (sum of all flows into it)
-  const getNodeInflowAmount = (nodeId, flows) => {
-    return flows
-      .filter(f => f.to_node_id === nodeId)
-      .reduce((sum, f) => sum + f.amount, 0);
-  };
-
   const nodeWidth = 140, nodeHeight = 48;
   const padding = { left: 50, top: 70, bottom: 30 };
 
   const columns = {
-    income: { x: padding.left, nodes: nodes.filter(n => n.type === 'income') },
-    account: { x: padding.left + 260, nodes: nodes.filter(n => n.type === 'account') },
-    distribution: { x: padding.left + 520, nodes: nodes.filter(n => ['savings', 'investment', 'expense', 'budget'].includes(n.type)) },
+    income: { x: padding.left, nodes: nodes.filter(n => n.column === 0) },
+    account: { x: padding.left + 260, nodes: nodes.filter(n => n.column === 1) },
+    distribution: { x: padding.left + 520, nodes: nodes.filter(n => n.column === 2) },
   };
 
   // Calculate maximum nodes in any column
@@ -136,7 +135,7 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
     return { x, y };
   };
 
-  // Color scheme by node type
+  // Legend colors by node type; nodes carry their own color from the server
   const nodeColors = {
     income: '#00ff41',      // Matrix green
     account: '#00d4ff',     // Cyan
@@ -144,6 +143,9 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
     investment: '#ff0080',  // Pink
     expense: '#ffb800',     // Amber
     budget: '#ffb800',      // Amber (same as expense)
+    credit_card: '#ff3b3b', // Red - payments reduce debt
+    loan: '#ff3b3b',
+    liability: '#ff3b3b',
   };
 
   // Calculate flow thickness based on amount
@@ -224,7 +226,7 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
         {flows.map((flow, i) => {
           const { path } = getPath(flow);
           const toNode = positionedNodes[flow.to_node_id];
-          const color = toNode ? nodeColors[toNode.type] : '#00ff41';
+          const color = toNode ? toNode.color : '#00ff41';
           const isHighlighted = hoveredFlow === i || hoveredNode === flow.from_node_id || hoveredNode === flow.to_node_id;
           const thickness = getFlowThickness(flow.amount);
 
@@ -288,7 +290,7 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
           if (!flow) return null;
           const pos = getPointOnPath(flow, p.progress);
           const toNode = positionedNodes[flow.to_node_id];
-          const color = toNode ? nodeColors[toNode.type] : '#00ff41';
+          const color = toNode ? toNode.color : '#00ff41';
 
           return (
             <g key={p.id}>
@@ -327,7 +329,7 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
 
         {/* Nodes */}
         {Object.values(positionedNodes).map((node) => {
-          const color = nodeColors[node.type];
+          const color = node.color;
           const isHovered = hoveredNode === node.id;
 
           return (
@@ -450,15 +452,7 @@ export const SankeyDiagram = ({ nodes = [], flows = [], onNodeClick }) => {
                 opacity="0.8"
                 pointerEvents="none"
               >
-                ${(() => {
-                  // For income nodes, use their amount (what they generate)
-                  if (node.type === 'income') {
-                    return (node.amount || 0).toLocaleString();
-                  }
-                  // For all other nodes, show total inflow (cashflow into the node)
-                  const inflow = getNodeInflowAmount(node.id, flows);
-                  return inflow.toLocaleString();
-                })()}
+                ${node.value.toLocaleString()}
               </text>
             </g>
           );